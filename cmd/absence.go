@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/spf13/cobra"
+)
+
+// absenceCmd represents the absence command
+var absenceCmd = &cobra.Command{
+	Use:   "absence",
+	Short: "Shows the absence of a Lectio student in each team",
+	Long: `Shows the absence (fravær) of a Lectio student in each team, both from modules and from written assignments, as a table or as JSON.
+
+With --threshold, a warning is printed for each team in which the absence so far is at or above the threshold, in percent.
+Set it a bit below the limit of your school (often 10 %) to be warned in time.
+
+Example:
+
+	lego absence --threshold 8
+` + credentialsHelp,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		threshold, _ := cmd.Flags().GetFloat64("threshold")
+
+		// Progress is logged to standard error, so the JSON output can be piped
+		l, err := newLectio(cmd, lectigo.WithLogger(log.New(os.Stderr, "lectio ", log.LstdFlags)))
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+		absence, err := l.GetAbsence()
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+		if err := l.SaveSession(); err != nil {
+			l.Logger.Printf("Could not save Lectio session: %v\n", err)
+		}
+
+		err = writeAbsence(os.Stdout, format, absence, threshold)
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+		for _, team := range overThreshold(absence, threshold) {
+			fmt.Fprintf(os.Stderr, "Warning: the absence in %s is %s %%, at or above the threshold of %s %%\n", team.Team, formatPercent(max(team.Modules.Percent, team.Written.Percent)), formatPercent(threshold))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(absenceCmd)
+
+	addLectioFlags(absenceCmd)
+	absenceCmd.Flags().StringP("format", "f", "table", "The output format (table or json)")
+	absenceCmd.Flags().Float64("threshold", 0, "Warn about teams with an absence at or above this percentage. 0 gives no warnings")
+}
+
+// The absence printed as JSON, with the teams at or above the threshold
+type absenceReport struct {
+	*lectigo.Absence
+	Threshold     float64  `json:"threshold,omitempty"`
+	OverThreshold []string `json:"overThreshold,omitempty"` // The teams at or above the threshold
+}
+
+// Returns the teams with an absence at or above the threshold. A threshold of 0 returns none
+func overThreshold(absence *lectigo.Absence, threshold float64) []lectigo.TeamAbsence {
+	if threshold <= 0 {
+		return nil
+	}
+	var teams []lectigo.TeamAbsence
+	for _, team := range absence.Teams {
+		if team.Exceeds(threshold) {
+			teams = append(teams, team)
+		}
+	}
+	return teams
+}
+
+// Writes the absence as a table or as JSON. Teams at or above the threshold are marked with "!" in the table
+func writeAbsence(w io.Writer, format string, absence *lectigo.Absence, threshold float64) error {
+	switch format {
+	case "json":
+		report := absenceReport{Absence: absence, Threshold: threshold}
+		for _, team := range overThreshold(absence, threshold) {
+			report.OverThreshold = append(report.OverThreshold, team.Team)
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "\tTEAM\tABSENCE\tMODULES\tYEAR\tWRITTEN\tSTUDENT HOURS\tYEAR")
+		row := func(mark string, team lectigo.TeamAbsence) {
+			fmt.Fprintf(tw, "%s\t%s\t%s %%\t%s\t%s %%\t%s %%\t%s\t%s %%\n", mark, team.Team,
+				formatPercent(team.Modules.Percent), formatFraction(team.Modules), formatPercent(team.ModulesYear.Percent),
+				formatPercent(team.Written.Percent), formatFraction(team.Written), formatPercent(team.WrittenYear.Percent))
+		}
+		for _, team := range absence.Teams {
+			mark := ""
+			if threshold > 0 && team.Exceeds(threshold) {
+				mark = "!"
+			}
+			row(mark, team)
+		}
+		row("", absence.Total)
+		return tw.Flush()
+	}
+	return fmt.Errorf("Unknown format %s. Use table or json", format)
+}
+
+// Formats a percentage with a decimal comma, as Lectio does (eg. "5,26")
+func formatPercent(p float64) string {
+	return strings.ReplaceAll(strconv.FormatFloat(p, 'f', 2, 64), ".", ",")
+}
+
+// Formats the absent part of a total (eg. "2/38")
+func formatFraction(c lectigo.AbsenceCount) string {
+	format := func(f float64) string {
+		return strings.ReplaceAll(strconv.FormatFloat(f, 'f', -1, 64), ".", ",")
+	}
+	return format(c.Absent) + "/" + format(c.Total)
+}
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+// authCmd represents the auth command
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Authorises lectigo to access Google Calendar",
+	Long: `Authorises lectigo to access Google Calendar and saves the OAuth token, which later commands use and refresh.
+
+By default a server is started on a random port of the loopback interface, and Google redirects the browser to it once access is granted.
+On servers and over SSH, where the browser cannot reach lectigo, use --manual: the address the browser ends up at is then pasted back into lectigo.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		manual, _ := cmd.Flags().GetBool("manual")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		settings := googleSettingsFromFlags(cmd)
+		oauthConfig, err := settings.oauthConfig()
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+		_, tokenPath, err := settings.filePaths()
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		var token *oauth2.Token
+		if manual {
+			token, err = util.AuthorizeManual(ctx, oauthConfig, os.Stdin, os.Stdout)
+		} else {
+			token, err = util.AuthorizeLoopback(ctx, oauthConfig, func(authURL string) {
+				fmt.Printf("Visit the following URL in a browser on this machine and allow lectigo access:\n\n%s\n\n", authURL)
+				fmt.Println("Waiting for the browser to be redirected back...")
+			})
+		}
+		if err != nil {
+			log.Fatalf("Could not authorise lectigo: %v\n", err)
+		}
+
+		err = util.SaveToken(tokenPath, token)
+		if err != nil {
+			log.Fatalf("Could not save token: %v\n", err)
+		}
+		fmt.Printf("Saved token to %s\n", tokenPath)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+
+	addOAuthFlags(authCmd)
+	authCmd.Flags().Bool("manual", false, "Paste the redirect address instead of starting a local server, for use over SSH or on servers")
+	authCmd.Flags().Duration("timeout", 5*time.Minute, "How long to wait for access to be granted")
+}
+
+// Adds the flags used to connect to Google Calendar to a command
+func addGoogleFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("calendarID", "c", "primary", "Google Calendar calendar ID")
+	addOAuthFlags(cmd)
+	cmd.Flags().String("service-account", "", "The path to a Google service account key file. Used instead of the user OAuth flow")
+	cmd.Flags().String("impersonate", "", "The email of the user the service account acts as (requires domain-wide delegation)")
+	cmd.Flags().String("calendar-url", "", "The base URL of the Google Calendar API")
+	cmd.Flags().MarkHidden("calendar-url")
+}
+
+// Adds the flags of the Google OAuth client credentials, token and scopes to a command
+func addOAuthFlags(cmd *cobra.Command) {
+	cmd.Flags().String("credentials", "", "The path to the Google OAuth client credentials file (default is credentials.json in the lectigo config directory)")
+	cmd.Flags().StringP("tokenPath", "t", "", "The path to the Google OAuth token file (default is token.json in the lectigo config directory)")
+	cmd.Flags().StringSlice("scope", []string{calendar.CalendarEventsScope}, "The OAuth scopes to request access to")
+
+	// --token is the old name of --tokenPath in the clear command
+	cmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "token" {
+			name = "tokenPath"
+		}
+		return pflag.NormalizedName(name)
+	})
+}
+
+// Returns the path of a file in the lectigo config directory. For compatibility with earlier versions, a file of the same name in the working directory is used if only that exists
+func configFilePath(name string) (string, error) {
+	dir, err := util.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if _, err := os.Stat(name); err == nil {
+			return name, nil
+		}
+	}
+	return path, nil
+}
+
+// The settings used to connect to Google Calendar
+type googleSettings struct {
+	CalendarID      string   // Google Calendar calendar ID
+	CredentialsPath string   // The path to the OAuth client credentials file. Empty uses the default
+	TokenPath       string   // The path to the OAuth token file. Empty uses the default
+	Scopes          []string // The OAuth scopes to request access to
+	ServiceAccount  string   // The path to a service account key file, used instead of the user OAuth flow if given
+	Impersonate     string   // The email of the user the service account acts as
+	Endpoint        string   // The base URL of the Google Calendar API. Empty uses the default
+}
+
+// Returns the Google Calendar settings given by the command flags. Flags the command does not have are left empty
+func googleSettingsFromFlags(cmd *cobra.Command) *googleSettings {
+	s := &googleSettings{}
+	s.CalendarID, _ = cmd.Flags().GetString("calendarID")
+	s.CredentialsPath, _ = cmd.Flags().GetString("credentials")
+	s.TokenPath, _ = cmd.Flags().GetString("tokenPath")
+	s.Scopes, _ = cmd.Flags().GetStringSlice("scope")
+	s.ServiceAccount, _ = cmd.Flags().GetString("service-account")
+	s.Impersonate, _ = cmd.Flags().GetString("impersonate")
+	s.Endpoint, _ = cmd.Flags().GetString("calendar-url")
+	return s
+}
+
+// Returns the paths of the OAuth client credentials and token files, or their defaults
+func (s *googleSettings) filePaths() (credentialsPath string, tokenPath string, err error) {
+	credentialsPath, tokenPath = s.CredentialsPath, s.TokenPath
+
+	if credentialsPath == "" {
+		credentialsPath, err = configFilePath("credentials.json")
+		if err != nil {
+			return "", "", err
+		}
+	}
+	if tokenPath == "" {
+		tokenPath, err = configFilePath("token.json")
+		if err != nil {
+			return "", "", err
+		}
+	}
+	if !strings.HasSuffix(tokenPath, ".json") {
+		tokenPath += ".json"
+	}
+	return credentialsPath, tokenPath, nil
+}
+
+// Returns the scopes of the settings, defaulting to access to calendar events
+func (s *googleSettings) scopes() []string {
+	if len(s.Scopes) == 0 {
+		return []string{calendar.CalendarEventsScope}
+	}
+	return s.Scopes
+}
+
+// Reads the OAuth client credentials file and creates an OAuth config from it
+func (s *googleSettings) oauthConfig() (*oauth2.Config, error) {
+	credentialsPath, _, err := s.filePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	// Reads the credentials file and creates a config from it - this is used to create the client
+	bytes, err := os.ReadFile(credentialsPath)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read contents of %s: %v", credentialsPath, err)
+	}
+
+	config, err := google.ConfigFromJSON(bytes, s.scopes()...)
+	if err != nil {
+		return nil, fmt.Errorf("Could not create config from %s: %v", credentialsPath, err)
+	}
+	return config, nil
+}
+
+// Returns an HTTP client authorised for Google Calendar.
+// A service account is used if one is given, and the user OAuth token otherwise
+func (s *googleSettings) client() (*http.Client, error) {
+	if s.ServiceAccount != "" {
+		return s.serviceAccountClient()
+	}
+
+	config, err := s.oauthConfig()
+	if err != nil {
+		return nil, err
+	}
+	_, tokenPath, err := s.filePaths()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(tokenPath); err != nil {
+		return nil, fmt.Errorf("No Google OAuth token at %s. Create one with lectigo auth --tokenPath %s", tokenPath, tokenPath)
+	}
+
+	client, err := util.GetClient(config, tokenPath)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get Google Calendar client: %v", err)
+	}
+	return client, nil
+}
+
+// Returns an HTTP client authorised as the service account, impersonating the user if given
+func (s *googleSettings) serviceAccountClient() (*http.Client, error) {
+	bytes, err := os.ReadFile(s.ServiceAccount)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read contents of %s: %v", s.ServiceAccount, err)
+	}
+
+	client, err := util.GetServiceAccountClient(bytes, s.Impersonate, s.scopes()...)
+	if err != nil {
+		return nil, fmt.Errorf("Could not create service account client from %s: %v", s.ServiceAccount, err)
+	}
+	return client, nil
+}
+
+// Connects to the Google Calendar of the settings
+func (s *googleSettings) newCalendar(opts ...lectigo.Option) (*lectigo.GoogleCalendar, error) {
+	client, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Endpoint != "" {
+		opts = append([]lectigo.Option{lectigo.WithCalendarOptions(option.WithEndpoint(s.Endpoint))}, opts...)
+	}
+	c, err := lectigo.NewGoogleCalendar(client, s.CalendarID, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Could not create Google Calendar instance: %v", err)
+	}
+	return c, nil
+}
+
+// Connects to the Google Calendar given by the command flags
+func newGoogleCalendar(cmd *cobra.Command, opts ...lectigo.Option) (*lectigo.GoogleCalendar, error) {
+	return googleSettingsFromFlags(cmd).newCalendar(opts...)
+}
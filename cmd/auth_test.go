@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Without a token, commands run by cron or systemd must fail at once instead of waiting for a browser
+func TestGoogleClientWithoutToken(t *testing.T) {
+	dir := t.TempDir()
+	credentials := filepath.Join(dir, "credentials.json")
+	err := os.WriteFile(credentials, []byte(`{"installed":{"client_id":"lectigo","client_secret":"hemmelig","auth_uri":"https://accounts.google.com/o/oauth2/auth","token_uri":"https://oauth2.googleapis.com/token","redirect_uris":["http://localhost"]}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &googleSettings{CredentialsPath: credentials, TokenPath: filepath.Join(dir, "token.json")}
+
+	done := make(chan error)
+	go func() {
+		_, err := s.client()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "lectigo auth") {
+			t.Errorf("Got error %v, want one pointing to lectigo auth", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Getting a client without a token is waiting for the browser")
+	}
+}
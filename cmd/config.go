@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/goccy/go-yaml"
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// The config file given by the --config flag
+var cfgFile string
+
+// The loaded config. Set before any command runs
+var config = &Config{}
+
+// Settings read from the lectigo config file (YAML, or TOML if the file ends in .toml). Command line flags override them
+type Config struct {
+	Username        string            `yaml:"username" toml:"username"`               // Lectio username
+	Password        string            `yaml:"password" toml:"password"`               // Lectio password. Prefer the keyring (lectigo login) over storing it here
+	SchoolID        string            `yaml:"schoolID" toml:"schoolID"`               // Lectio school ID
+	CalendarID      string            `yaml:"calendarID" toml:"calendarID"`           // Google Calendar calendar ID
+	TokenPath       string            `yaml:"tokenPath" toml:"tokenPath"`             // The path to the Google OAuth token file
+	CredentialsPath string            `yaml:"credentialsPath" toml:"credentialsPath"` // The path to the Google OAuth client credentials file
+	Scopes          []string          `yaml:"scopes" toml:"scopes"`                   // The OAuth scopes to request access to
+	ServiceAccount  string            `yaml:"serviceAccount" toml:"serviceAccount"`   // The path to a Google service account key file, used instead of the user OAuth flow
+	Impersonate     string            `yaml:"impersonate" toml:"impersonate"`         // The email of the user the service account acts as
+	Weeks           int               `yaml:"weeks" toml:"weeks"`                     // Amount of weeks to sync
+	FullHomework    bool              `yaml:"fullHomework" toml:"fullHomework"`       // Get the full homework of modules from their activity pages
+	Notes           bool              `yaml:"notes" toml:"notes"`                     // Get the note and other content of modules from their activity pages
+	DayEvents       bool              `yaml:"dayEvents" toml:"dayEvents"`             // Also sync day events (eg. excursions) as all-day events
+	Teacher         string            `yaml:"teacher" toml:"teacher"`                 // Sync the schedule of the teacher with this Lectio ID instead of the user's own
+	Room            string            `yaml:"room" toml:"room"`                       // Sync the booking schedule of the room with this Lectio ID instead of the user's own
+	Class           string            `yaml:"class" toml:"class"`                     // Sync the schedule of the class with this Lectio ID instead of the user's own
+	Assignments     AssignmentsConfig `yaml:"assignments" toml:"assignments"`
+	Timezone        string            `yaml:"timezone" toml:"timezone"` // The timezone of the school
+	Session         string            `yaml:"session" toml:"session"`   // The path to the encrypted Lectio session file
+	Keyring         string            `yaml:"keyring" toml:"keyring"`   // The keyring to look up the Lectio password in
+	Filters         FilterConfig      `yaml:"filters" toml:"filters"`
+	Templates       TemplatesConfig   `yaml:"templates" toml:"templates"`
+	Daemon          DaemonConfig      `yaml:"daemon" toml:"daemon"`
+	Notify          NotifyConfig      `yaml:"notify" toml:"notify"`
+}
+
+// Settings deciding which modules are synced
+type FilterConfig struct {
+	Include         []string `yaml:"include" toml:"include"`                 // Regular expressions. If given, only modules with a matching title are synced
+	Exclude         []string `yaml:"exclude" toml:"exclude"`                 // Regular expressions. Modules with a matching title are not synced
+	ExcludeStatuses []string `yaml:"excludeStatuses" toml:"excludeStatuses"` // Statuses (eg. "aflyst") of modules not to sync
+}
+
+// Templates of the calendar events of modules (text/template, executed with the module)
+type TemplatesConfig struct {
+	Summary     string `yaml:"summary" toml:"summary"`
+	Description string `yaml:"description" toml:"description"`
+}
+
+// Settings of the syncing of assignment deadlines
+type AssignmentsConfig struct {
+	Sync   bool `yaml:"sync" toml:"sync"`     // Also sync the deadlines of assignments
+	AllDay bool `yaml:"allDay" toml:"allDay"` // Sync assignment deadlines as all-day events
+}
+
+// Settings of the daemon command
+type DaemonConfig struct {
+	Interval   string   `yaml:"interval" toml:"interval"`     // The time between syncs (eg. "15m")
+	Jitter     string   `yaml:"jitter" toml:"jitter"`         // The longest random delay added to each interval
+	QuietHours []string `yaml:"quietHours" toml:"quietHours"` // Daily periods without syncs (eg. "22:00-06:00")
+	QuietDays  []string `yaml:"quietDays" toml:"quietDays"`   // Weekdays without syncs (eg. "sat")
+	HealthFile string   `yaml:"healthFile" toml:"healthFile"` // The path of the health file
+}
+
+// Returns the paths searched for a config file when --config is not given, in order
+func defaultConfigPaths() ([]string, error) {
+	var paths []string
+	dir, err := util.ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	paths = append(paths, filepath.Join(dir, "config.yaml"), filepath.Join(dir, "config.toml"))
+
+	home, err := os.UserHomeDir()
+	if err == nil {
+		paths = append(paths, filepath.Join(home, ".lectigo.yaml"))
+	}
+	return paths, nil
+}
+
+// Returns the config file to use: the one given by --config, or else the first existing default path. Returns an empty path if there is none
+func configPath() (string, error) {
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+	paths, err := defaultConfigPaths()
+	if err != nil {
+		return "", err
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// Reads the config file. Without a config file the config is empty. A config file given with --config must exist
+func loadConfig() (*Config, error) {
+	config := &Config{}
+
+	path, err := configPath()
+	if err != nil || path == "" {
+		return config, err
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && cfgFile == "" {
+			return config, nil
+		}
+		return nil, err
+	}
+
+	err = unmarshalFile(path, b, config)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse config file %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// Decodes the contents of a YAML file, or of a TOML file if the path ends in .toml
+func unmarshalFile(path string, b []byte, v any) error {
+	if strings.HasSuffix(path, ".toml") {
+		return toml.Unmarshal(b, v)
+	}
+	return yaml.Unmarshal(b, v)
+}
+
+// Returns the config values by the names of the flags they set
+func (c *Config) flagValues() map[string]string {
+	values := map[string]string{
+		"calendarID":      c.CalendarID,
+		"tokenPath":       c.TokenPath,
+		"credentials":     c.CredentialsPath,
+		"service-account": c.ServiceAccount,
+		"impersonate":     c.Impersonate,
+		"timezone":        c.Timezone,
+		"session":         c.Session,
+		"keyring":         c.Keyring,
+		"interval":        c.Daemon.Interval,
+		"jitter":          c.Daemon.Jitter,
+		"quiet":           strings.Join(c.Daemon.QuietHours, ","),
+		"quiet-days":      strings.Join(c.Daemon.QuietDays, ","),
+		"health-file":     c.Daemon.HealthFile,
+		"teacher":         c.Teacher,
+		"room":            c.Room,
+		"class":           c.Class,
+	}
+	if len(c.Scopes) > 0 {
+		values["scope"] = strings.Join(c.Scopes, ",")
+	}
+	if c.FullHomework {
+		values["full-homework"] = "true"
+	}
+	if c.Notes {
+		values["notes"] = "true"
+	}
+	if c.DayEvents {
+		values["day-events"] = "true"
+	}
+	if c.Assignments.Sync {
+		values["assignments"] = "true"
+	}
+	if c.Assignments.AllDay {
+		values["assignments-all-day"] = "true"
+	}
+	if c.Weeks > 0 {
+		values["weeks"] = strconv.Itoa(c.Weeks)
+	}
+	return values
+}
+
+// Sets the flags of the command, which are not given on the command line, to the values of the config
+func (c *Config) applyToFlags(cmd *cobra.Command) error {
+	var errs []error
+	values := c.flagValues()
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		value := values[f.Name]
+		if value == "" || f.Changed {
+			return
+		}
+		if err := f.Value.Set(value); err != nil {
+			errs = append(errs, fmt.Errorf("Invalid %s in config file: %v", f.Name, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// Returns the module filter of the config, or nil if it filters nothing
+func (c *Config) moduleFilter() (*lectigo.ModuleFilter, error) {
+	f := c.Filters
+	if len(f.Include) == 0 && len(f.Exclude) == 0 && len(f.ExcludeStatuses) == 0 {
+		return nil, nil
+	}
+	return lectigo.NewModuleFilter(f.Include, f.Exclude, f.ExcludeStatuses)
+}
+
+// Returns the event template of the config, or nil if the defaults are used
+func (c *Config) eventTemplate() (*lectigo.EventTemplate, error) {
+	t := c.Templates
+	if t.Summary == "" && t.Description == "" {
+		return nil, nil
+	}
+	return lectigo.NewEventTemplate(t.Summary, t.Description)
+}
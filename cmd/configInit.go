@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/mattismoel/lectigo/util"
+	"github.com/spf13/cobra"
+)
+
+// The starter config file written by config init
+var starterConfig = template.Must(template.New("config").Funcs(template.FuncMap{"quote": strconv.Quote}).Parse(`# lectigo config file. Command line flags override these settings.
+
+# Lectio login. The password is best saved with "lectigo login" instead of here
+schoolID: {{quote .SchoolID}}
+username: {{quote .Username}}
+
+# Google Calendar
+calendarID: {{quote .CalendarID}}
+# The Google OAuth files. Defaults to token.json and credentials.json in the lectigo config directory
+# tokenPath: "token.json"
+# credentialsPath: "credentials.json"
+# scopes: ["https://www.googleapis.com/auth/calendar.events"]
+# Sync with a Google service account acting as a user instead of logging in with OAuth
+# serviceAccount: "key.json"
+# impersonate: "student@school.dk"
+
+# Amount of weeks to sync
+weeks: {{.Weeks}}
+
+# Get the full homework and its links from the activity page of each module, instead of the shortened homework of the schedule
+# fullHomework: true
+# Add the note and other content (Øvrigt indhold) of each module to the event description
+# notes: true
+
+# Also sync day events, such as excursions and exam days, as all-day events
+# dayEvents: true
+
+# Sync the schedule of a teacher, room or class instead of your own, by its Lectio ID (see lego list). Use a calendar of its own for it
+# teacher: "9001"
+
+# Also sync the deadlines of assignments, at the time of the deadline or as all-day events
+# assignments:
+#   sync: true
+#   allDay: false
+
+# The timezone of the school
+timezone: {{quote .Timezone}}
+
+# Which modules to sync. Include and exclude are regular expressions matching module titles
+# filters:
+#   include: ["^3a"]
+#   exclude: ["Studievejledning"]
+#   excludeStatuses: ["aflyst"]
+
+# Templates of the calendar events (Go text/template). Available fields are .Title, .Teacher, .Room, .Homework, .Links, .Note, .Content, .ModuleStatus, .StartDate and .EndDate
+# templates:
+#   summary: "{{"{{"}}.Title{{"}}"}} ({{"{{"}}.Room{{"}}"}})"
+#   description: |
+#     Lærer: {{"{{"}}.Teacher{{"}}"}}
+#     Lektier:
+#     {{"{{"}}.Homework{{"}}"}}
+
+# Settings of lectigo daemon. The quiet hours are in the timezone of the school
+# daemon:
+#   interval: "15m"
+#   jitter: "1m"
+#   quietHours: ["22:00-06:00"]
+#   quietDays: ["sat", "sun"]
+
+# Notifications about modules becoming "aflyst" or "ændret", or changing room or time, in the coming days. Sent by sync and daemon
+# notify:
+#   days: 7
+#   messages: true # Also notify about new unread Lectio messages. Only done by daemon
+#   webhook:
+#     url: "https://example.com/hook"
+#   push:
+#     url: "https://ntfy.sh/my-lectio-topic"
+#   email:
+#     host: "smtp.example.com:587"
+#     username: "me@example.com"
+#     from: "me@example.com"
+#     to: ["me@example.com"]
+`))
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manages the lectigo config file",
+	// The config file is not loaded, so a broken config file can be replaced
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+}
+
+// configInitCmd represents the config init command
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Writes a starter config file",
+	Long: `Asks for the most common settings and writes a starter config file with them.
+The file is written to the path given by --config, or config.yaml in the lectigo config directory.
+
+Example:
+
+	lego config init`,
+	Run: func(cmd *cobra.Command, args []string) {
+		force, _ := cmd.Flags().GetBool("force")
+
+		path := cfgFile
+		if path == "" {
+			dir, err := util.ConfigDir()
+			if err != nil {
+				log.Fatalf("Could not find config directory: %v\n", err)
+			}
+			path = filepath.Join(dir, "config.yaml")
+		}
+		if strings.HasSuffix(path, ".toml") {
+			log.Fatalf("config init writes YAML. Use a path ending in .yaml\n")
+		}
+
+		if _, err := os.Stat(path); err == nil && !force {
+			log.Fatalf("Config file %s already exists. Use --force to overwrite it\n", path)
+		}
+
+		stdin := bufio.NewReader(os.Stdin)
+		values := struct {
+			SchoolID   string
+			Username   string
+			CalendarID string
+			Weeks      int
+			Timezone   string
+		}{}
+
+		var err error
+		ask := func(message, defaultValue string) string {
+			if err != nil {
+				return ""
+			}
+			if defaultValue != "" {
+				message = fmt.Sprintf("%s [%s]", message, defaultValue)
+			}
+			var answer string
+			answer, err = prompt(stdin, message+": ")
+			if answer == "" {
+				return defaultValue
+			}
+			return answer
+		}
+
+		values.SchoolID = ask("Lectio school ID (see lectigo listSchools)", "")
+		values.Username = ask("Lectio username", "")
+		values.CalendarID = ask("Google Calendar ID", "primary")
+		weeks := ask("Weeks to sync", "2")
+		values.Timezone = ask("School timezone", util.DefaultTimezone)
+		if err != nil {
+			log.Fatalf("Could not read answer: %v\n", err)
+		}
+
+		values.Weeks, err = strconv.Atoi(weeks)
+		if err != nil || values.Weeks < 1 {
+			log.Fatalf("Invalid amount of weeks %q\n", weeks)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			log.Fatalf("Could not create config directory: %v\n", err)
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			log.Fatalf("Could not create config file: %v\n", err)
+		}
+		defer f.Close()
+
+		if err := starterConfig.Execute(f, values); err != nil {
+			log.Fatalf("Could not write config file: %v\n", err)
+		}
+		fmt.Printf("Wrote config file to %s\n", path)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configInitCmd)
+
+	configInitCmd.Flags().Bool("force", false, "Overwrite an existing config file")
+}
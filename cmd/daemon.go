@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/util"
+	"github.com/spf13/cobra"
+)
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Keeps syncing a Lectio schedule with a Google Calendar on an interval",
+	Long: `Syncs a Lectio schedule with a Google Calendar right away, and then again every interval until stopped with SIGTERM or Ctrl+C.
+
+The Lectio session and the Google Calendar client are kept between syncs, so lectigo only logs in again when the session expires.
+A random delay of up to --jitter is added to each interval, so many daemons do not hit Lectio at the same time.
+No syncs are made in the quiet hours (eg. --quiet 22:00-06:00 --quiet-days sat,sun), which are given in the timezone of the school.
+
+With messages: true in the notify section of the config file, new unread Lectio messages are notified about after each sync.
+
+The health file is rewritten after every sync with the status of the daemon as JSON, for monitoring.
+` + credentialsHelp,
+	Run: func(cmd *cobra.Command, args []string) {
+		interval, _ := cmd.Flags().GetDuration("interval")
+		jitter, _ := cmd.Flags().GetDuration("jitter")
+		quietPeriods, _ := cmd.Flags().GetStringSlice("quiet")
+		quietDays, _ := cmd.Flags().GetStringSlice("quiet-days")
+		healthFile, _ := cmd.Flags().GetString("health-file")
+
+		if interval <= 0 {
+			log.Fatalf("The interval must be positive\n")
+		}
+		quiet, err := util.ParseQuietHours(quietPeriods, quietDays)
+		if err != nil {
+			log.Fatalf("Could not parse quiet hours: %v\n", err)
+		}
+		opts, err := syncOptionsFromFlags(cmd)
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+		template, err := config.eventTemplate()
+		if err != nil {
+			log.Fatalf("Could not parse event templates from config: %v\n", err)
+		}
+		notifiers, err := config.Notify.notifiers()
+		if err != nil {
+			log.Fatalf("Could not set up notifications from config: %v\n", err)
+		}
+		location, err := schoolLocation(cmd)
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+		if healthFile == "" {
+			dir, err := util.ConfigDir()
+			if err != nil {
+				log.Fatalf("%v\n", err)
+			}
+			healthFile = filepath.Join(dir, "health.json")
+		}
+
+		c, err := newGoogleCalendar(cmd, lectigo.WithLocation(location), lectigo.WithEventTemplate(template))
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+
+		var l *lectigo.Lectio
+		d := &daemon{
+			Clock:      util.SystemClock,
+			Location:   location,
+			Interval:   interval,
+			Jitter:     jitter,
+			Quiet:      quiet,
+			HealthFile: healthFile,
+			Logger:     log.New(os.Stdout, "daemon ", log.LstdFlags),
+			Sync: func() (*lectigo.SyncResult, error) {
+				// Logs in on the first sync, and again after a sync that lost the session. Other errors, such as a failed calendar update, keep it
+				if l == nil {
+					lectio, loginErr := newLectio(cmd)
+					if loginErr != nil {
+						return nil, loginErr
+					}
+					l = lectio
+				}
+				result, syncErr := syncSchedule(l, c, opts)
+				notifyChanges(notifiers, result, config.Notify.Days, c.Clock)
+				if lectioSessionLost(syncErr) {
+					l = nil
+				} else if syncErr == nil && config.Notify.Messages {
+					notifyMessages(l, notifiers)
+				}
+				return result, syncErr
+			},
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		err = d.run(ctx)
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+
+	addLectioFlags(daemonCmd)
+	addSyncFlags(daemonCmd)
+	addGoogleFlags(daemonCmd)
+	daemonCmd.Flags().Duration("interval", 15*time.Minute, "The time between syncs")
+	daemonCmd.Flags().Duration("jitter", time.Minute, "The longest random delay added to each interval")
+	daemonCmd.Flags().StringSlice("quiet", nil, "Daily periods without syncs, eg. 22:00-06:00 (can be repeated)")
+	daemonCmd.Flags().StringSlice("quiet-days", nil, "Weekdays without syncs, eg. sat,sun")
+	daemonCmd.Flags().String("health-file", "", "The path of the health file (default is health.json in the lectigo config directory)")
+}
+
+// Reports whether a sync failed because the Lectio session can no longer be used, so the daemon should log in again.
+// An expired session shows the login page in place of the schedule
+func lectioSessionLost(err error) bool {
+	return errors.Is(err, lectigo.ErrLoginRequired) ||
+		errors.Is(err, lectigo.ErrLectioUnavailable) ||
+		errors.Is(err, lectigo.ErrScheduleNotFound)
+}
+
+// Runs a sync on an interval
+type daemon struct {
+	Clock      util.Clock
+	Location   *time.Location   // The location the quiet hours are given in
+	Interval   time.Duration    // The time between syncs
+	Jitter     time.Duration    // The longest random delay added to each interval
+	Quiet      *util.QuietHours // Times in which syncs are skipped
+	HealthFile string           // The path the health of the daemon is written to. Empty writes no health file
+	Logger     *log.Logger
+	Sync       func() (*lectigo.SyncResult, error) // Syncs once
+
+	health daemonHealth
+}
+
+// The status of the daemon written to the health file
+type daemonHealth struct {
+	Status              string              `json:"status"` // "starting", "ok", "failing", "quiet" or "stopped"
+	PID                 int                 `json:"pid"`
+	StartedAt           time.Time           `json:"startedAt"`
+	LastRun             *time.Time          `json:"lastRun,omitempty"`
+	LastSuccess         *time.Time          `json:"lastSuccess,omitempty"`
+	NextRun             *time.Time          `json:"nextRun,omitempty"`
+	LastError           string              `json:"lastError,omitempty"`
+	LastResult          *lectigo.SyncResult `json:"lastResult,omitempty"`
+	ConsecutiveFailures int                 `json:"consecutiveFailures"`
+}
+
+// Syncs right away and then once every interval, until the context is done. A sync in progress is finished before returning
+func (d *daemon) run(ctx context.Context) error {
+	d.health = daemonHealth{Status: "starting", PID: os.Getpid(), StartedAt: d.Clock.Now()}
+
+	for {
+		d.runOnce()
+
+		next := d.Clock.Now().Add(d.wait())
+		d.health.NextRun = &next
+		d.writeHealth()
+
+		select {
+		case <-ctx.Done():
+			d.Logger.Println("Stopping")
+			d.health.Status = "stopped"
+			d.health.NextRun = nil
+			d.writeHealth()
+			return nil
+		case <-d.Clock.After(next.Sub(d.Clock.Now())):
+		}
+	}
+}
+
+// Syncs once, unless it is within the quiet hours
+func (d *daemon) runOnce() {
+	now := d.Clock.Now()
+	if d.Quiet != nil && d.Quiet.Contains(now.In(d.Location)) {
+		d.Logger.Println("Skipping sync in quiet hours")
+		d.health.Status = "quiet"
+		return
+	}
+
+	result, err := d.Sync()
+	if result != nil {
+		d.Logger.Println(result)
+	}
+
+	d.health.LastRun = &now
+	d.health.LastResult = result
+	if err != nil {
+		d.Logger.Printf("Sync failed: %v\n", err)
+		d.health.Status = "failing"
+		d.health.LastError = err.Error()
+		d.health.ConsecutiveFailures++
+		return
+	}
+	d.health.Status = "ok"
+	d.health.LastError = ""
+	d.health.LastSuccess = &now
+	d.health.ConsecutiveFailures = 0
+}
+
+// Returns the time until the next sync: the interval plus a random jitter
+func (d *daemon) wait() time.Duration {
+	if d.Jitter <= 0 {
+		return d.Interval
+	}
+	return d.Interval + time.Duration(rand.Int63n(int64(d.Jitter)))
+}
+
+// Writes the health of the daemon to the health file. The file is replaced at once, so readers never see it half written
+func (d *daemon) writeHealth() {
+	if d.HealthFile == "" {
+		return
+	}
+	err := writeFileAtomic(d.HealthFile, d.health)
+	if err != nil {
+		d.Logger.Printf("Could not write health file: %v\n", err)
+	}
+}
+
+// Writes the value as JSON to a temporary file next to the path, and renames it to the path
+func writeFileAtomic(path string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, append(b, '\n'), 0600)
+	if err != nil {
+		return fmt.Errorf("Could not write %s: %v", tmp, err)
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/pkg/lectigo/googlecalendartest"
+	"github.com/mattismoel/lectigo/util"
+)
+
+// Runs the daemon on a fake clock from start until end, moving the clock a minute at a time once the daemon is waiting.
+// Returns the times of the syncs and the health of the daemon when stopped
+func runDaemon(t *testing.T, d *daemon, clock *util.FakeClock, end time.Time) ([]time.Time, daemonHealth) {
+	t.Helper()
+	var mu sync.Mutex
+	var syncs []time.Time
+	d.Clock = clock
+	d.Logger = log.New(io.Discard, "", 0)
+	d.HealthFile = filepath.Join(t.TempDir(), "health.json")
+	d.Sync = func() (*lectigo.SyncResult, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		syncs = append(syncs, clock.Now())
+		return &lectigo.SyncResult{}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- d.run(ctx)
+	}()
+
+	waitForDaemon := func() {
+		deadline := time.Now().Add(5 * time.Second)
+		for clock.Waiters() == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("The daemon is not waiting for the clock")
+			}
+			runtime.Gosched()
+		}
+	}
+	for clock.Now().Before(end) {
+		waitForDaemon()
+		clock.Advance(time.Minute)
+	}
+	waitForDaemon()
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(d.HealthFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var health daemonHealth
+	if err := json.Unmarshal(b, &health); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return syncs, health
+}
+
+func TestDaemonQuietHoursAndJitter(t *testing.T) {
+	location, err := time.LoadLocation(util.DefaultTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	quiet, err := util.ParseQuietHours([]string{"22:00-06:00"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		start time.Time
+	}{
+		{"winter", time.Date(2025, time.January, 13, 18, 0, 0, 0, location)},
+		// The nights the clocks change
+		{"last saturday of march", time.Date(2025, time.March, 29, 18, 0, 0, 0, location)},
+		{"last saturday of october", time.Date(2025, time.October, 25, 18, 0, 0, 0, location)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := &daemon{Location: location, Interval: time.Hour, Jitter: 10 * time.Minute, Quiet: quiet}
+			clock := util.NewFakeClock(test.start)
+			syncs, health := runDaemon(t, d, clock, test.start.Add(18*time.Hour))
+
+			if len(syncs) == 0 {
+				t.Fatal("No syncs made")
+			}
+			if !syncs[0].Equal(test.start) {
+				t.Errorf("First sync at %v, want right away at %v", syncs[0], test.start)
+			}
+			evening, morning := 0, 0
+			for i, s := range syncs {
+				local := s.In(location)
+				if quiet.Contains(local) {
+					t.Errorf("Sync in quiet hours at %v", local.Format("2006-01-02 15:04 MST"))
+				}
+				if local.Day() == test.start.Day() {
+					evening++
+				} else {
+					morning++
+				}
+				if i == 0 {
+					continue
+				}
+				// Syncs are an interval plus up to the jitter apart, unless the quiet hours came between them
+				gap := s.Sub(syncs[i-1])
+				if gap < d.Interval {
+					t.Errorf("Syncs at %v and %v are only %v apart", syncs[i-1].In(location), local, gap)
+				}
+				if gap > d.Interval+d.Jitter+time.Minute && local.Hour() != 6 && local.Hour() != 7 {
+					t.Errorf("Syncs at %v and %v are %v apart, more than the interval and jitter", syncs[i-1].In(location), local, gap)
+				}
+			}
+			if morning == 0 {
+				t.Errorf("No syncs after the quiet hours ended")
+			}
+			// From 18:00 to 22:00 there is room for 4 syncs, at most one interval and jitter apart
+			if evening < 3 || evening > 4 {
+				t.Errorf("Made %d syncs before the quiet hours, want 3 or 4", evening)
+			}
+
+			if health.Status != "stopped" || health.LastSuccess == nil || health.ConsecutiveFailures != 0 {
+				t.Errorf("Got health %+v, want stopped after successful syncs", health)
+			}
+		})
+	}
+}
+
+func TestDaemonHealthInQuietHours(t *testing.T) {
+	location, err := time.LoadLocation(util.DefaultTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	quiet, err := util.ParseQuietHours(nil, []string{"sat", "sun"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &daemon{Location: location, Interval: 15 * time.Minute, Quiet: quiet}
+	start := time.Date(2025, time.January, 18, 12, 0, 0, 0, location) // Saturday
+
+	syncs, health := runDaemon(t, d, util.NewFakeClock(start), start.Add(time.Hour))
+	if len(syncs) != 0 {
+		t.Errorf("Made %d syncs on a quiet day", len(syncs))
+	}
+	if health.LastRun != nil || health.NextRun != nil {
+		t.Errorf("Got health %+v, want no runs", health)
+	}
+}
+
+// The daemon keeps its Lectio session after a failed calendar update, and logs in again after Lectio lost the session or was unavailable
+func TestLectioSessionLost(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(f *syncFixture)
+		lost  bool
+	}{
+		{"calendar insert failed", func(f *syncFixture) {
+			f.calendarServer.Fail(googlecalendartest.Method(http.MethodPost), http.StatusConflict, 1)
+		}, false},
+		{"lectio unavailable", func(f *syncFixture) { f.lectioServer.SetUnavailable(true) }, true},
+		{"session expired", func(f *syncFixture) {
+			f.lectioServer.ExpireSessions()
+			f.lectioServer.ExpireAutologins()
+		}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newSyncFixture(t, 2024, time.December, 11)
+			test.setup(f)
+			_, err := syncSchedule(f.lectio, f.calendar, syncOptions{Weeks: 2})
+			if err == nil {
+				t.Fatal("Sync succeeded")
+			}
+			if lost := lectioSessionLost(err); lost != test.lost {
+				t.Errorf("lectioSessionLost(%v) = %v, want %v", err, lost, test.lost)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/util"
+	"github.com/spf13/cobra"
+)
+
+// gradesCmd represents the grades command
+var gradesCmd = &cobra.Command{
+	Use:   "grades",
+	Short: "Exports the grades of a Lectio student",
+	Long: `Exports the grades (karakterer) of a Lectio student: term grades, annual grades and exam grades with their weights. Available formats are:
+
+json, csv, yaml, xml
+
+The grades are written to standard output, or to a file at the path given with --path. The extension of the format is added to the path if missing.
+
+With --average, the average of the annual and exam grades, weighted by their weights, is added. Use --average-of to choose the kinds of grades to average (term, annual, exam or other).
+Grades not on the 7-point scale, such as "Bestået", are left out of the average.
+
+Example:
+
+	lego grades -f csv -o ./karakterer --average
+` + credentialsHelp,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		path, _ := cmd.Flags().GetString("path")
+		average, _ := cmd.Flags().GetBool("average")
+		averageOf, _ := cmd.Flags().GetStringSlice("average-of")
+
+		var kinds []lectigo.GradeKind
+		for _, k := range averageOf {
+			kind := lectigo.GradeKind(k)
+			switch kind {
+			case lectigo.GradeTerm, lectigo.GradeAnnual, lectigo.GradeExam, lectigo.GradeOther:
+				kinds = append(kinds, kind)
+			default:
+				log.Fatalf("Unknown kind of grade %q. Use term, annual, exam or other\n", k)
+			}
+		}
+
+		// Progress is logged to standard error, so the output can be piped
+		l, err := newLectio(cmd, lectigo.WithLogger(log.New(os.Stderr, "lectio ", log.LstdFlags)))
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+		grades, err := l.GetGrades()
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+		if err := l.SaveSession(); err != nil {
+			l.Logger.Printf("Could not save Lectio session: %v\n", err)
+		}
+
+		report := &gradesReport{Grades: grades}
+		if average {
+			if avg, ok := lectigo.WeightedAverage(grades, kinds...); ok {
+				report.Average = &avg
+			} else {
+				l.Logger.Printf("No grades to average\n")
+			}
+		}
+
+		if path == "" {
+			err = util.Encode(os.Stdout, format, report)
+		} else {
+			err = util.ExportFile(format, path, report)
+		}
+		if err != nil {
+			log.Fatalf("Could not export grades to %v format: %v\n", format, err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gradesCmd)
+
+	addLectioFlags(gradesCmd)
+	gradesCmd.Flags().StringP("format", "f", "json", "The format of which the grades should be exported as (json, csv, yaml or xml)")
+	gradesCmd.Flags().StringP("path", "o", "", "The path to which the grades should be exported. Standard output if empty")
+	gradesCmd.Flags().Bool("average", false, "Add the weighted average of the grades")
+	gradesCmd.Flags().StringSlice("average-of", []string{string(lectigo.GradeAnnual), string(lectigo.GradeExam)}, "The kinds of grades to average (term, annual, exam or other)")
+}
+
+// The exported grades, with their weighted average if requested
+type gradesReport struct {
+	XMLName xml.Name        `json:"-" yaml:"-" xml:"grades"`
+	Grades  []lectigo.Grade `json:"grades" yaml:"grades" xml:"grade"`
+	Average *float64        `json:"average,omitempty" yaml:"average,omitempty" xml:"average,omitempty"`
+}
+
+// Returns the grades as CSV rows under a header. The average is added as a last row without team and subject
+func (r *gradesReport) MarshalCSV() ([][]string, error) {
+	records := [][]string{{"team", "subject", "kind", "column", "grade", "weight"}}
+	for _, g := range r.Grades {
+		records = append(records, []string{g.Team, g.Subject, string(g.Kind), g.Column, g.Grade, strconv.FormatFloat(g.Weight, 'f', -1, 64)})
+	}
+	if r.Average != nil {
+		records = append(records, []string{"", "", "average", "", strconv.FormatFloat(*r.Average, 'f', 2, 64), ""})
+	}
+	return records, nil
+}
@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/util"
+	"github.com/spf13/cobra"
+)
+
+// Describes where the Lectio login information is looked up. Added to the help of commands logging in to Lectio
+const credentialsHelp = `
+The Lectio login information is looked up in the following order, using the first value found:
+
+  Username and school ID:
+    1. the --username and --schoolID flags
+    2. the LECTIGO_USERNAME and LECTIGO_SCHOOL_ID environment variables
+    3. the config file (username and schoolID)
+    4. the account last saved with "lectigo login"
+
+  Password:
+    1. the --password flag (not recommended, as it is visible in the shell history and process list)
+    2. standard input, if --password-stdin is given
+    3. the LECTIGO_PASSWORD environment variable
+    4. the config file (password)
+    5. the system keyring, then the encrypted keyring file, as saved by "lectigo login"
+
+If no password is found, the saved Lectio session is used. It lasts for a long time after logging in once.`
+
+// The keyring user under which the account of the last "lectigo login" is saved
+const defaultAccountUser = "default"
+
+// Adds the flags used to log in to Lectio to a command
+func addLectioFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("username", "u", "", "Lectio username")
+	cmd.Flags().StringP("password", "p", "", "Lectio password (prefer --password-stdin, LECTIGO_PASSWORD or lectigo login)")
+	cmd.Flags().Bool("password-stdin", false, "Read the Lectio password from standard input")
+	cmd.Flags().StringP("schoolID", "s", "", "Lectio school ID")
+	cmd.Flags().String("timezone", util.DefaultTimezone, "The timezone of the school")
+	cmd.Flags().String("session", "", "The path to the encrypted Lectio session file (default is session in the lectigo config directory)")
+	cmd.Flags().Bool("no-session", false, "Log in every time instead of saving and reusing the Lectio session")
+	cmd.Flags().String("keyring", "auto", "The keyring to look up the Lectio password in (auto, system or file)")
+	cmd.Flags().String("lectio-url", lectigo.DefaultLectioBaseURL, "The base URL of Lectio")
+	cmd.Flags().MarkHidden("lectio-url")
+}
+
+// Returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Looks up the Lectio username and school ID not given by the flags in the order described by credentialsHelp. Either is empty if not found
+func lectioAccount(username, schoolID string, keyrings []util.Keyring) (string, string) {
+	username = firstNonEmpty(username, os.Getenv("LECTIGO_USERNAME"), config.Username)
+	schoolID = firstNonEmpty(schoolID, os.Getenv("LECTIGO_SCHOOL_ID"), config.SchoolID)
+	if username == "" || schoolID == "" {
+		if account, err := keyringGet(keyrings, defaultAccountUser); err == nil {
+			accountSchoolID, accountUsername, _ := strings.Cut(account, "/")
+			username = firstNonEmpty(username, accountUsername)
+			schoolID = firstNonEmpty(schoolID, accountSchoolID)
+		}
+	}
+	return username, schoolID
+}
+
+// Looks up the Lectio login information in the order described by credentialsHelp
+func lectioLoginInfo(cmd *cobra.Command) (*lectigo.LectioLoginInfo, error) {
+	username, _ := cmd.Flags().GetString("username")
+	password, _ := cmd.Flags().GetString("password")
+	passwordStdin, _ := cmd.Flags().GetBool("password-stdin")
+	schoolID, _ := cmd.Flags().GetString("schoolID")
+	keyringKind, _ := cmd.Flags().GetString("keyring")
+
+	keyrings, err := openKeyrings(keyringKind)
+	if err != nil {
+		return nil, err
+	}
+
+	username, schoolID = lectioAccount(username, schoolID, keyrings)
+	if username == "" {
+		return nil, errors.New("No Lectio username given. Use --username, LECTIGO_USERNAME, the config file or lectigo login")
+	}
+	if schoolID == "" {
+		return nil, errors.New("No Lectio school ID given. Use --schoolID, LECTIGO_SCHOOL_ID, the config file or lectigo login")
+	}
+
+	if password == "" && passwordStdin {
+		password, err = readPassword(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read password from standard input: %v", err)
+		}
+	}
+	password = firstNonEmpty(password, os.Getenv("LECTIGO_PASSWORD"), config.Password)
+	if password == "" {
+		password, err = keyringGet(keyrings, keyringAccount(schoolID, username))
+		if err != nil && !errors.Is(err, util.ErrSecretNotFound) {
+			return nil, err
+		}
+	}
+
+	return &lectigo.LectioLoginInfo{
+		Username: username,
+		Password: password,
+		SchoolID: schoolID,
+	}, nil
+}
+
+// Returns the school timezone given by the --timezone flag
+func schoolLocation(cmd *cobra.Command) (*time.Location, error) {
+	timezone, _ := cmd.Flags().GetString("timezone")
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("Could not load timezone %q: %v", timezone, err)
+	}
+	return location, nil
+}
+
+// Logs in to Lectio with the login information and session settings of the command flags. Extra options are applied last
+func newLectio(cmd *cobra.Command, opts ...lectigo.Option) (*lectigo.Lectio, error) {
+	sessionPath, _ := cmd.Flags().GetString("session")
+	noSession, _ := cmd.Flags().GetBool("no-session")
+	baseURL, _ := cmd.Flags().GetString("lectio-url")
+
+	loginInfo, err := lectioLoginInfo(cmd)
+	if err != nil {
+		return nil, err
+	}
+	location, err := schoolLocation(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	lectioOpts := []lectigo.Option{lectigo.WithLocation(location), lectigo.WithBaseURL(baseURL)}
+	if !noSession {
+		store, err := openSessionStore(sessionPath)
+		if err != nil {
+			return nil, fmt.Errorf("Could not open Lectio session file: %v", err)
+		}
+		lectioOpts = append(lectioOpts, lectigo.WithSession(store))
+	}
+	return openLectio(loginInfo, append(lectioOpts, opts...)...)
+}
+
+// Logs in to Lectio, describing why if the login fails
+func openLectio(loginInfo *lectigo.LectioLoginInfo, opts ...lectigo.Option) (*lectigo.Lectio, error) {
+	l, err := lectigo.NewLectio(loginInfo, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Could not log in to Lectio: %s", lectioLoginMessage(err))
+	}
+	return l, nil
+}
+
+// Returns a message telling the user what went wrong when logging in to Lectio
+func lectioLoginMessage(err error) string {
+	switch {
+	case errors.Is(err, lectigo.ErrInvalidCredentials):
+		return "the username or password is wrong"
+	case errors.Is(err, lectigo.ErrUnknownSchool):
+		return "the school ID does not exist. Use the listSchools command to find the ID of your school"
+	case errors.Is(err, lectigo.ErrLoginRequired):
+		return "the saved session has expired. Log in again with lectigo login or by giving your password"
+	case errors.Is(err, lectigo.ErrLectioUnavailable):
+		return fmt.Sprintf("Lectio could not be reached. Try again later (%v)", err)
+	}
+	return err.Error()
+}
+
+// Opens the Lectio session store at the given path, defaulting to the lectigo config directory. The key is kept next to the session file
+func openSessionStore(path string) (*lectigo.SessionStore, error) {
+	if path == "" {
+		dir, err := util.ConfigDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, "session")
+	}
+	return lectigo.NewSessionStore(path, path+".key")
+}
+
+// Opens the keyrings of the given kind in the order they are searched. "auto" gives the system keyring followed by the encrypted keyring file
+func openKeyrings(kind string) ([]util.Keyring, error) {
+	var keyrings []util.Keyring
+	if kind == "auto" || kind == "system" {
+		keyrings = append(keyrings, util.SystemKeyring{})
+	}
+	if kind == "auto" || kind == "file" {
+		dir, err := util.ConfigDir()
+		if err != nil {
+			return nil, err
+		}
+		keyrings = append(keyrings, util.NewFileKeyring(filepath.Join(dir, "keyring"), filepath.Join(dir, "keyring.key")))
+	}
+	if len(keyrings) == 0 {
+		return nil, fmt.Errorf("Unknown keyring %q. Use auto, system or file", kind)
+	}
+	return keyrings, nil
+}
+
+// Returns the secret of the user from the first keyring holding it. Unavailable keyrings are skipped
+func keyringGet(keyrings []util.Keyring, user string) (string, error) {
+	for _, keyring := range keyrings {
+		secret, err := keyring.Get(util.KeyringService, user)
+		if err == nil {
+			return secret, nil
+		}
+	}
+	return "", util.ErrSecretNotFound
+}
+
+// Returns the keyring user of a Lectio account
+func keyringAccount(schoolID, username string) string {
+	return schoolID + "/" + username
+}
+
+// Reads a password from the first line of the reader
+func readPassword(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mattismoel/lectigo/util"
+)
+
+func TestLectioAccount(t *testing.T) {
+	dir := t.TempDir()
+	keyring := util.NewFileKeyring(filepath.Join(dir, "keyring"), filepath.Join(dir, "key"))
+	if err := keyring.Set(util.KeyringService, defaultAccountUser, keyringAccount("681", "saved")); err != nil {
+		t.Fatal(err)
+	}
+	keyrings := []util.Keyring{keyring}
+
+	tests := []struct {
+		name                 string
+		username, schoolID   string // Given by the flags
+		envUsername, envID   string
+		config               Config
+		wantUser, wantSchool string
+	}{
+		{"flags", "flag", "133", "env", "243", Config{Username: "config", SchoolID: "517"}, "flag", "133"},
+		{"environment", "", "", "env", "243", Config{Username: "config", SchoolID: "517"}, "env", "243"},
+		{"config", "", "", "", "", Config{Username: "config", SchoolID: "517"}, "config", "517"},
+		{"saved account", "", "", "", "", Config{}, "saved", "681"},
+		{"mixed", "flag", "", "", "", Config{SchoolID: "517"}, "flag", "517"},
+	}
+	defer func(c *Config) { config = c }(config)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("LECTIGO_USERNAME", test.envUsername)
+			t.Setenv("LECTIGO_SCHOOL_ID", test.envID)
+			config = &test.config
+
+			username, schoolID := lectioAccount(test.username, test.schoolID, keyrings)
+			if username != test.wantUser || schoolID != test.wantSchool {
+				t.Errorf("Got %s at %s, want %s at %s", username, schoolID, test.wantUser, test.wantSchool)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/util"
+	"github.com/spf13/cobra"
+)
+
+// The kinds of schedules the list command lists by its argument, with the XML element of each entity
+var listKinds = map[string]struct {
+	Kind    lectigo.ScheduleTargetKind
+	Element string
+}{
+	"teachers": {lectigo.TargetTeacher, "teacher"},
+	"rooms":    {lectigo.TargetRoom, "room"},
+	"classes":  {lectigo.TargetClass, "class"},
+}
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:       "list teachers|rooms|classes",
+	Short:     "Lists the teachers, rooms or classes of a school with their Lectio IDs",
+	ValidArgs: []string{"teachers", "rooms", "classes"},
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs, checkListFormat),
+	Long: `Lists the teachers, rooms or classes with a schedule at a Lectio school, with the IDs to give to --teacher, --room and --class. Available formats are:
+
+json, yaml, xml
+
+The list is written to standard output, or to a file at the path given with --path. The extension of the format is added to the path if missing.
+No login is needed, as the lists are public.
+
+Example:
+
+	lego list teachers -s 133 -f yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		path, _ := cmd.Flags().GetString("path")
+		schoolID, _ := cmd.Flags().GetString("schoolID")
+		baseURL, _ := cmd.Flags().GetString("lectio-url")
+
+		schoolID = firstNonEmpty(schoolID, os.Getenv("LECTIGO_SCHOOL_ID"), config.SchoolID)
+		if schoolID == "" {
+			log.Fatalf("No Lectio school ID given. Use --schoolID, LECTIGO_SCHOOL_ID or the config file\n")
+		}
+
+		entities, err := lectigo.GetScheduleEntities(nil, baseURL, schoolID, listKinds[args[0]].Kind)
+		if err != nil {
+			log.Fatalf("Could not list %s of school %s: %v\n", args[0], schoolID, err)
+		}
+
+		var v any = entities
+		if format == "xml" {
+			v = newEntityList(args[0], listKinds[args[0]].Element, entities)
+		}
+		if path == "" {
+			err = util.Encode(os.Stdout, format, v)
+		} else {
+			err = util.ExportFile(format, path, v)
+		}
+		if err != nil {
+			log.Fatalf("Could not export %s to %v format: %v\n", args[0], format, err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().StringP("schoolID", "s", "", "Lectio school ID")
+	listCmd.Flags().StringP("format", "f", "json", "The format of which the list should be exported as (json, yaml or xml)")
+	listCmd.Flags().StringP("path", "o", "", "The path to which the list should be exported. Standard output if empty")
+	listCmd.Flags().String("lectio-url", lectigo.DefaultLectioBaseURL, "The base URL of Lectio")
+	listCmd.Flags().MarkHidden("lectio-url")
+}
+
+// Returns an error if the format flag is not one the entities can be exported as, before anything is fetched or written
+func checkListFormat(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	switch format {
+	case "json", "yaml", "xml":
+		return nil
+	}
+	return fmt.Errorf("Cannot list %s as %q. Use json, yaml or xml", args[0], format)
+}
+
+// The entities exported as XML, named after what they are (eg. <teachers><teacher>...</teacher></teachers>)
+type entityList struct {
+	XMLName  xml.Name
+	Entities []entityElement
+}
+
+type entityElement struct {
+	XMLName xml.Name
+	lectigo.ScheduleEntity
+}
+
+func newEntityList(name, element string, entities []lectigo.ScheduleEntity) *entityList {
+	list := &entityList{XMLName: xml.Name{Local: name}}
+	for _, e := range entities {
+		list.Entities = append(list.Entities, entityElement{XMLName: xml.Name{Local: element}, ScheduleEntity: e})
+	}
+	return list
+}
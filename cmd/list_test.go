@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestListArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr bool
+	}{
+		{[]string{"teachers"}, false},
+		{[]string{"rooms", "-f", "yaml"}, false},
+		{[]string{"classes", "-f", "xml"}, false},
+		{[]string{"teachers", "-f", "csv"}, true},
+		{[]string{"teachers", "-f", "toml"}, true},
+		{[]string{"students"}, true},
+	}
+	for _, test := range tests {
+		cmd := &cobra.Command{ValidArgs: listCmd.ValidArgs}
+		cmd.Flags().StringP("format", "f", "json", "")
+		if err := cmd.Flags().Parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+		err := listCmd.Args(cmd, cmd.Flags().Args())
+		if (err != nil) != test.wantErr {
+			t.Errorf("Got error %v of %v, want an error: %v", err, test.args, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/mattismoel/lectigo/util"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// loginCmd represents the login command
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Logs in to Lectio and saves the password in the keyring",
+	Long: `Logs in to Lectio and saves the password in the system keyring (Secret Service on Linux), so later commands do not need it.
+If no system keyring is available, the password is saved in a file encrypted with a key in the lectigo config directory.
+
+The Lectio session is saved as well, and the account becomes the default account used when no username is given.
+The username, school ID and password are looked up like in other commands (see below), except that the password is not read from the keyring.
+Login information that is not found is asked for interactively. The password is never echoed.
+
+Example:
+
+	lego login -u username1234 -s 133
+` + credentialsHelp,
+	Run: func(cmd *cobra.Command, args []string) {
+		username, _ := cmd.Flags().GetString("username")
+		schoolID, _ := cmd.Flags().GetString("schoolID")
+		password, _ := cmd.Flags().GetString("password")
+		passwordStdin, _ := cmd.Flags().GetBool("password-stdin")
+		keyringKind, _ := cmd.Flags().GetString("keyring")
+
+		keyrings, err := openKeyrings(keyringKind)
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+
+		// Only the login information that cannot be looked up like in any other command is asked for
+		username, schoolID = lectioAccount(username, schoolID, keyrings)
+		stdin := bufio.NewReader(os.Stdin)
+		if username == "" {
+			username, err = prompt(stdin, "Lectio username: ")
+			if err != nil {
+				log.Fatalf("Could not read username: %v\n", err)
+			}
+		}
+		if schoolID == "" {
+			schoolID, err = prompt(stdin, "Lectio school ID: ")
+			if err != nil {
+				log.Fatalf("Could not read school ID: %v\n", err)
+			}
+		}
+		if password == "" && !passwordStdin {
+			password = firstNonEmpty(os.Getenv("LECTIGO_PASSWORD"), config.Password)
+		}
+		if password == "" {
+			if passwordStdin {
+				password, err = readPassword(stdin)
+			} else {
+				password, err = promptPassword("Lectio password: ")
+			}
+			if err != nil {
+				log.Fatalf("Could not read password: %v\n", err)
+			}
+		}
+
+		// The login information is passed on through the flags, so it is looked up like in any other command
+		cmd.Flags().Set("username", username)
+		cmd.Flags().Set("schoolID", schoolID)
+		cmd.Flags().Set("password", password)
+		cmd.Flags().Set("password-stdin", "false")
+
+		// A saved session would be reused without checking the password, so it is cleared to make sure the password works
+		if noSession, _ := cmd.Flags().GetBool("no-session"); !noSession {
+			sessionPath, _ := cmd.Flags().GetString("session")
+			store, err := openSessionStore(sessionPath)
+			if err == nil {
+				err = store.Clear()
+			}
+			if err != nil {
+				log.Fatalf("Could not clear saved Lectio session: %v\n", err)
+			}
+		}
+
+		l, err := newLectio(cmd)
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+		if err := l.SaveSession(); err != nil {
+			log.Printf("Could not save Lectio session: %v\n", err)
+		}
+
+		var saveErrs []error
+		for _, keyring := range keyrings {
+			err := keyring.Set(util.KeyringService, keyringAccount(schoolID, username), password)
+			if err == nil {
+				err = keyring.Set(util.KeyringService, defaultAccountUser, keyringAccount(schoolID, username))
+			}
+			if err == nil {
+				fmt.Printf("Logged in as %s and saved the password in the %s\n", username, keyringName(keyring))
+				return
+			}
+			saveErrs = append(saveErrs, fmt.Errorf("%s: %v", keyringName(keyring), err))
+		}
+		log.Fatalf("Logged in, but could not save the password: %v\n", errors.Join(saveErrs...))
+	},
+}
+
+// Returns a readable name of a keyring
+func keyringName(keyring util.Keyring) string {
+	if fileKeyring, ok := keyring.(*util.FileKeyring); ok {
+		return fmt.Sprintf("encrypted keyring file %s", fileKeyring.Path)
+	}
+	return "system keyring"
+}
+
+// Asks the user for a line of input
+func prompt(stdin *bufio.Reader, message string) (string, error) {
+	fmt.Print(message)
+	line, err := stdin.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// Asks the user for a password without echoing it. Only works when standard input is a terminal
+func promptPassword(message string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errors.New("standard input is not a terminal. Use --password-stdin to pipe the password")
+	}
+	fmt.Print(message)
+	b, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func init() {
+	rootCmd.AddCommand(loginCmd)
+
+	addLectioFlags(loginCmd)
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/spf13/cobra"
+)
+
+// messagesCmd represents the messages command
+var messagesCmd = &cobra.Command{
+	Use:   "messages [thread ID]",
+	Short: "Lists unread Lectio messages, or shows a message thread",
+	Long: `Lists the unread message threads (beskeder) of a Lectio user, or all the newest threads with --all.
+
+Given the ID of a thread, shows the messages of the thread as plain text. Lectio marks the thread as read.
+Messages are only read, never sent or deleted.
+
+Example:
+
+	lego messages
+	lego messages 61000800
+` + credentialsHelp,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		all, _ := cmd.Flags().GetBool("all")
+		if format != "text" && format != "json" {
+			log.Fatalf("Unknown format %s. Use text or json\n", format)
+		}
+
+		// Progress is logged to standard error, so the JSON output can be piped
+		l, err := newLectio(cmd, lectigo.WithLogger(log.New(os.Stderr, "lectio ", log.LstdFlags)))
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+		defer func() {
+			if err := l.SaveSession(); err != nil {
+				l.Logger.Printf("Could not save Lectio session: %v\n", err)
+			}
+		}()
+
+		if len(args) == 1 {
+			thread, err := l.GetMessageThread(args[0])
+			if err != nil {
+				log.Fatalf("%v\n", err)
+			}
+			if format == "json" {
+				err = writeJSON(os.Stdout, thread)
+			} else {
+				err = writeThread(os.Stdout, thread)
+			}
+			if err != nil {
+				log.Fatalf("%v\n", err)
+			}
+			return
+		}
+
+		threads, err := l.GetMessages()
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+		if !all {
+			var unread []lectigo.MessageThread
+			for _, t := range threads {
+				if t.Unread {
+					unread = append(unread, t)
+				}
+			}
+			threads = unread
+		}
+		if format == "json" {
+			err = writeJSON(os.Stdout, threads)
+		} else {
+			err = writeThreads(os.Stdout, threads)
+		}
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(messagesCmd)
+
+	addLectioFlags(messagesCmd)
+	messagesCmd.Flags().StringP("format", "f", "text", "The output format (text or json)")
+	messagesCmd.Flags().Bool("all", false, "List read threads too")
+}
+
+// Writes the value as indented JSON
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// Writes the threads as a table. Unread threads are marked with "*"
+func writeThreads(w io.Writer, threads []lectigo.MessageThread) error {
+	if len(threads) == 0 {
+		_, err := fmt.Fprintln(w, "No messages")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tID\tLATEST\tFROM\tSUBJECT")
+	for _, t := range threads {
+		mark := ""
+		if t.Unread {
+			mark = "*"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", mark, t.Id, t.Latest.Format("02/01-2006 15:04"), t.From, t.Subject)
+	}
+	return tw.Flush()
+}
+
+// Writes the messages of the thread as plain text, oldest first
+func writeThread(w io.Writer, t *lectigo.MessageThread) error {
+	fmt.Fprintf(w, "%s\n", t.Subject)
+	if t.To != "" {
+		fmt.Fprintf(w, "Til: %s\n", t.To)
+	}
+	for _, m := range t.Messages {
+		fmt.Fprintf(w, "\n--- %s, %s ---\n", m.From, m.Time.Format("02/01-2006 15:04"))
+		if m.Subject != "" && m.Subject != t.Subject {
+			fmt.Fprintf(w, "%s\n\n", m.Subject)
+		}
+		fmt.Fprintf(w, "%s\n", m.Body)
+		if len(m.Links) > 0 {
+			fmt.Fprintf(w, "\nLinks:\n")
+			for _, link := range m.Links {
+				fmt.Fprintf(w, "%s\n", link)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/util"
+)
+
+// The amount of days ahead changes are notified about, if the config does not set it
+const defaultNotifyDays = 7
+
+// Settings of the notifications about changes to modules, sent by sync and daemon. Each sink is used if its URL or host is set
+type NotifyConfig struct {
+	Days     int           `yaml:"days" toml:"days"`         // Only changes to modules in this many days from now are notified about
+	Messages bool          `yaml:"messages" toml:"messages"` // Also notify about new unread Lectio messages. Only done by daemon
+	Webhook  WebhookConfig `yaml:"webhook" toml:"webhook"`
+	Email    EmailConfig   `yaml:"email" toml:"email"`
+	Push     PushConfig    `yaml:"push" toml:"push"`
+}
+
+// A URL the changes are posted to as JSON
+type WebhookConfig struct {
+	URL      string `yaml:"url" toml:"url"`
+	Template string `yaml:"template" toml:"template"` // The template of the request body. Defaults to {"changes": [...]}
+}
+
+// An email the changes are sent in
+type EmailConfig struct {
+	Host     string   `yaml:"host" toml:"host"`         // The SMTP server (eg. "smtp.example.com:587")
+	Username string   `yaml:"username" toml:"username"` // The SMTP username. Without it, no authentication is used
+	Password string   `yaml:"password" toml:"password"` // The SMTP password. Can also be given by LECTIGO_SMTP_PASSWORD
+	From     string   `yaml:"from" toml:"from"`
+	To       []string `yaml:"to" toml:"to"`
+	Subject  string   `yaml:"subject" toml:"subject"`
+	Body     string   `yaml:"body" toml:"body"`
+}
+
+// A push endpoint (eg. an ntfy topic) the changes are posted to as plain text
+type PushConfig struct {
+	URL   string `yaml:"url" toml:"url"`
+	Title string `yaml:"title" toml:"title"`
+	Body  string `yaml:"body" toml:"body"`
+}
+
+// Returns the notifiers of the sinks set up in the config
+func (c *NotifyConfig) notifiers() ([]lectigo.Notifier, error) {
+	var notifiers []lectigo.Notifier
+
+	if c.Webhook.URL != "" {
+		n, err := lectigo.NewWebhookNotifier(c.Webhook.URL, c.Webhook.Template)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, n)
+	}
+
+	if e := c.Email; e.Host != "" {
+		if e.From == "" || len(e.To) == 0 {
+			return nil, fmt.Errorf("The email notification needs a from and to address")
+		}
+		var auth smtp.Auth
+		if e.Username != "" {
+			host, _, err := net.SplitHostPort(e.Host)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid SMTP host %q: %v", e.Host, err)
+			}
+			auth = smtp.PlainAuth("", e.Username, firstNonEmpty(e.Password, os.Getenv("LECTIGO_SMTP_PASSWORD")), host)
+		}
+		n, err := lectigo.NewEmailNotifier(e.Host, auth, e.From, e.To, e.Subject, e.Body)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, n)
+	}
+
+	if c.Push.URL != "" {
+		n, err := lectigo.NewPushNotifier(c.Push.URL, c.Push.Title, c.Push.Body)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, n)
+	}
+
+	return notifiers, nil
+}
+
+// Sends the changes of a sync to the modules in the coming days to the notifiers. Failing notifiers are logged, as the sync itself went well
+func notifyChanges(notifiers []lectigo.Notifier, result *lectigo.SyncResult, days int, clock util.Clock) {
+	if len(notifiers) == 0 || result == nil {
+		return
+	}
+	if days <= 0 {
+		days = defaultNotifyDays
+	}
+
+	changes := lectigo.ChangesWithin(result.Changes, clock.Now(), days)
+	if len(changes) == 0 {
+		return
+	}
+	for _, n := range notifiers {
+		err := n.Notify(changes)
+		if err != nil {
+			log.Printf("Could not send notification: %v\n", err)
+		}
+	}
+}
+
+// Sends the unread message threads that are new, or have new messages, since the last time to the notifiers.
+// The threads seen are kept in a file in the lectigo config directory. The first time, the threads are only recorded, so old messages are not notified about
+func notifyMessages(l *lectigo.Lectio, notifiers []lectigo.Notifier) {
+	var messageNotifiers []lectigo.MessageNotifier
+	for _, n := range notifiers {
+		if mn, ok := n.(lectigo.MessageNotifier); ok {
+			messageNotifiers = append(messageNotifiers, mn)
+		}
+	}
+	if len(messageNotifiers) == 0 {
+		return
+	}
+
+	dir, err := util.ConfigDir()
+	if err != nil {
+		log.Printf("Could not check for new messages: %v\n", err)
+		return
+	}
+	path := filepath.Join(dir, "messages", l.LoginInfo.SchoolID+"-"+l.LoginInfo.Username+".json")
+
+	threads, err := l.GetMessages()
+	if err != nil {
+		log.Printf("Could not check for new messages: %v\n", err)
+		return
+	}
+
+	// The time of the latest message of each thread seen
+	seen := make(map[string]time.Time)
+	b, err := os.ReadFile(path)
+	first := errors.Is(err, os.ErrNotExist)
+	if err == nil {
+		err = json.Unmarshal(b, &seen)
+	}
+	if err != nil && !first {
+		log.Printf("Could not read seen messages, so all unread messages are new: %v\n", err)
+	}
+
+	var unseen []lectigo.MessageThread
+	for _, t := range threads {
+		latest, ok := seen[t.Id]
+		if t.Unread && (!ok || t.Latest.After(latest)) {
+			unseen = append(unseen, t)
+		}
+	}
+
+	if len(unseen) > 0 && !first {
+		for _, n := range messageNotifiers {
+			err := n.NotifyMessages(unseen)
+			if err != nil {
+				log.Printf("Could not send notification: %v\n", err)
+			}
+		}
+	}
+
+	// Only the threads still listed are kept, so the file does not grow
+	latest := make(map[string]time.Time)
+	for _, t := range threads {
+		latest[t.Id] = t.Latest
+	}
+	err = writeFileAtomic(path, latest)
+	if err != nil {
+		log.Printf("Could not save seen messages: %v\n", err)
+	}
+}
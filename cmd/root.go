@@ -17,6 +17,15 @@ var rootCmd = &cobra.Command{
 	Use:   "lectigo",
 	Short: "A synchronisation tool for Lectio and Google Calendar",
 	Long: `Synchronises a users schedule from Danish school administrative site MaCom Lectio with a Google Calendar`,
+	// Loads the config file and sets flags not given on the command line from it
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		loaded, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		config = loaded
+		return config.applyToFlags(cmd)
+	},
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
@@ -32,16 +41,11 @@ func Execute() {
 }
 
 func init() {
-	syncCmd.PersistentFlags().StringP("calendarID", "c", "primary", "Google Calendar calendar ID")
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.lectigo.yaml)")
-
-	// Cobra also supports local flags, which will only run
-	// when this action is called directly.
-	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file, YAML or TOML (default is config.yaml in the lectigo config directory, or $HOME/.lectigo.yaml)")
 }
 
 
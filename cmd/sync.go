@@ -4,16 +4,15 @@ Copyright © 2023 Mattis Kristensen <mattismoel@gmail.com>
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"log"
-	"os"
-	"strings"
+	"path/filepath"
+	"time"
 
 	"github.com/mattismoel/lectigo/pkg/lectigo"
 	"github.com/mattismoel/lectigo/util"
 	"github.com/spf13/cobra"
-	"golang.org/x/oauth2/google"
-	"google.golang.org/api/calendar/v3"
 )
 
 // var (
@@ -29,77 +28,231 @@ import (
 var syncCmd = &cobra.Command{
 	Use:   "sync",
 	Short: "Syncs a Lectio schedule with a Google Calendar",
-	Long:  `Synchronises a users Lectio scedule with Google Calendar. The users Lectio login info as well as Google Calendar info is provided.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		username, _ := cmd.Flags().GetString("username")
-		password, _ := cmd.Flags().GetString("password")
-		schoolID, _ := cmd.Flags().GetString("schoolID")
-		calendarID, _ := cmd.Flags().GetString("calendarID")
-		tokenPath, _ := cmd.Flags().GetString("tokenPath")
-		weeks, _ := cmd.Flags().GetInt("weeks")
-
-		fmt.Println("Attempting to sync Lectio and Google Calendar...")
+	Long: `Synchronises a users Lectio scedule with Google Calendar. The users Lectio login info as well as Google Calendar info is provided.
 
-		// Reads the credentials file and creates a config from it - this is used to create the client
-		bytes, err := os.ReadFile("credentials.json")
+The Lectio session is saved in an encrypted file and reused by later runs, so the password is only needed when the session has expired.
+` + credentialsHelp,
+	Run: func(cmd *cobra.Command, args []string) {
+		opts, err := syncOptionsFromFlags(cmd)
 		if err != nil {
-			log.Fatalf("Could not read contents of credentials.json: %v\n", err)
+			log.Fatalf("%v\n", err)
 		}
-
-		config, err := google.ConfigFromJSON(bytes, calendar.CalendarEventsScope)
+		template, err := config.eventTemplate()
 		if err != nil {
-			log.Fatalf("Could not create config from credentials.json")
+			log.Fatalf("Could not parse event templates from config: %v\n", err)
 		}
 
-		if !strings.HasSuffix(tokenPath, ".json") {
-			tokenPath += ".json"
+		notifiers, err := config.Notify.notifiers()
+		if err != nil {
+			log.Fatalf("Could not set up notifications from config: %v\n", err)
 		}
 
-		client, err := util.GetClient(config, tokenPath)
+		location, err := schoolLocation(cmd)
 		if err != nil {
-			log.Fatalf("Could not get Google Calendar client: %v\n", err)
+			log.Fatalf("%v\n", err)
 		}
 
-		c, err := lectigo.NewGoogleCalendar(client, calendarID)
+		fmt.Println("Attempting to sync Lectio and Google Calendar...")
+
+		c, err := newGoogleCalendar(cmd, lectigo.WithLocation(location), lectigo.WithEventTemplate(template))
 		if err != nil {
-			log.Fatalf("Could not create Google Calendar instance: %v\n", err)
+			log.Fatalf("%v\n", err)
 		}
-		l, err := lectigo.NewLectio(&lectigo.LectioLoginInfo{
-			Username: username,
-			Password: password,
-			SchoolID: schoolID,
-		})
+		l, err := newLectio(cmd)
 		if err != nil {
-			log.Fatalf("Could not create Lectio instance: %v\n", err)
+			log.Fatalf("%v\n", err)
 		}
 
-		lModules, err := l.GetScheduleWeeks(weeks)
+		result, err := syncSchedule(l, c, opts)
+		if result != nil {
+			fmt.Println(result)
+		}
+		notifyChanges(notifiers, result, config.Notify.Days, c.Clock)
 		if err != nil {
-			log.Fatalf("Could not get Lectio schedule: %v\n", err)
+			log.Fatalf("%v\n", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+
+	addLectioFlags(syncCmd)
+	addSyncFlags(syncCmd)
+	addGoogleFlags(syncCmd)
+}
+
+// How long the activity of an unchanged module is cached. The schedule only shows the beginning of the homework and notes, so changes further in are found after this long
+const activityCacheAge = 24 * time.Hour
+
+// What syncSchedule syncs
+type syncOptions struct {
+	Weeks             int                    // Amount of weeks to sync
+	Filter            *lectigo.ModuleFilter  // Removes modules not to sync. Nil syncs all modules
+	Assignments       bool                   // Also sync the deadlines of assignments
+	AssignmentsAllDay bool                   // Sync assignment deadlines as all-day events
+	FullHomework      bool                   // Get the full homework of modules from their activity pages
+	Notes             bool                   // Get the note and other content of modules from their activity pages
+	DayEvents         bool                   // Also sync day events (eg. excursions) as all-day events
+	Target            lectigo.ScheduleTarget // Whose schedule to sync. The zero value syncs the schedule of the logged in user
+}
+
+// Returns the sync options given by the command flags and the config
+func syncOptionsFromFlags(cmd *cobra.Command) (syncOptions, error) {
+	o := syncOptions{}
+	o.Weeks, _ = cmd.Flags().GetInt("weeks")
+	o.Assignments, _ = cmd.Flags().GetBool("assignments")
+	o.AssignmentsAllDay, _ = cmd.Flags().GetBool("assignments-all-day")
+	o.FullHomework, _ = cmd.Flags().GetBool("full-homework")
+	o.Notes, _ = cmd.Flags().GetBool("notes")
+	o.DayEvents, _ = cmd.Flags().GetBool("day-events")
+
+	// A target given on the command line replaces the target of the config file
+	targetFlags := []string{"teacher", "room", "class"}
+	commandLine := false
+	for _, name := range targetFlags {
+		commandLine = commandLine || cmd.Flags().Changed(name)
+	}
+	var ids []string
+	for _, name := range targetFlags {
+		id, _ := cmd.Flags().GetString(name)
+		if commandLine && !cmd.Flags().Changed(name) {
+			id = ""
+		}
+		ids = append(ids, id)
+	}
+	target, err := scheduleTarget(ids[0], ids[1], ids[2])
+	if err != nil {
+		return o, err
+	}
+	o.Target = target
+	if !target.IsSelf() && o.Assignments {
+		if cmd.Flags().Changed("assignments") {
+			return o, errors.New("--assignments cannot be used with --teacher, --room or --class, as the assignments are those of the logged in student")
+		}
+		// Assignments turned on in the config are left out of the schedules of others
+		o.Assignments = false
+	}
+
+	filter, err := config.moduleFilter()
+	if err != nil {
+		return o, fmt.Errorf("Could not create module filter from config: %v", err)
+	}
+	o.Filter = filter
+	return o, nil
+}
+
+// Adds the flags of what to sync to a command
+func addSyncFlags(cmd *cobra.Command) {
+	cmd.Flags().IntP("weeks", "w", 2, "Amount of weeks to sync")
+	cmd.Flags().Bool("assignments", false, "Also sync the deadlines of assignments")
+	cmd.Flags().Bool("assignments-all-day", false, "Sync assignment deadlines as all-day events instead of at the time of the deadline")
+	cmd.Flags().Bool("full-homework", false, "Get the full homework and its links from the activity page of each module with homework")
+	cmd.Flags().Bool("notes", false, "Get the note and other content (Øvrigt indhold) from the activity page of each module, and add them to the event description")
+	cmd.Flags().Bool("day-events", false, "Also sync day events, such as excursions and exam days, as all-day events")
+	cmd.Flags().String("teacher", "", "Sync the schedule of the teacher with this Lectio ID instead of your own")
+	cmd.Flags().String("room", "", "Sync the booking schedule of the room with this Lectio ID instead of your own")
+	cmd.Flags().String("class", "", "Sync the schedule of the class with this Lectio ID instead of your own")
+}
+
+// Returns the schedule target given by the Lectio ID of a teacher, room or class. Only one may be given. Without any, the target is the logged in user
+func scheduleTarget(teacher, room, class string) (lectigo.ScheduleTarget, error) {
+	var targets []lectigo.ScheduleTarget
+	for _, t := range []lectigo.ScheduleTarget{
+		{Kind: lectigo.TargetTeacher, Id: teacher},
+		{Kind: lectigo.TargetRoom, Id: room},
+		{Kind: lectigo.TargetClass, Id: class},
+	} {
+		if t.Id != "" {
+			targets = append(targets, t)
 		}
+	}
+	if len(targets) > 1 {
+		return lectigo.ScheduleTarget{}, fmt.Errorf("Only one of teacher, room and class can be given")
+	}
+	if len(targets) == 0 {
+		return lectigo.ScheduleTarget{}, nil
+	}
+	return targets[0], nil
+}
 
-		gEvents, err := c.GetEvents(weeks)
+// Syncs the Lectio schedule of the coming weeks to Google Calendar, leaving out the modules the filter removes, and the day events and assignment deadlines if enabled.
+// The Lectio session is saved afterwards. The result is returned if the calendar was updated, even if some events failed
+func syncSchedule(l *lectigo.Lectio, c *lectigo.GoogleCalendar, o syncOptions) (*lectigo.SyncResult, error) {
+	lModules, dayEvents, err := l.GetTargetScheduleWeeks(o.Target, o.Weeks)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get Lectio schedule: %w", err)
+	}
+	if o.Filter != nil {
+		lModules = o.Filter.Apply(lModules)
+	}
+	if o.FullHomework || o.Notes {
+		fillActivities(l, lModules, o)
+	}
+
+	gEvents, err := c.GetEvents(o.Weeks)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get events from Google Calendar: %v", err)
+	}
+	result, updateErr := c.UpdateCalendar(lModules, gEvents)
+	if updateErr != nil {
+		updateErr = fmt.Errorf("Could not update Google Calendar: %v", updateErr)
+	}
+
+	if o.DayEvents {
+		dayEventResult, err := c.SyncDayEvents(dayEvents, o.Weeks)
+		result.Add(dayEventResult)
 		if err != nil {
-			log.Fatalf("Could not get events from Google Calendar: %v\n", err)
+			updateErr = errors.Join(updateErr, fmt.Errorf("Could not update day events in Google Calendar: %v", err))
 		}
-		err = c.UpdateCalendar(lModules, gEvents)
+	}
+
+	// The assignments are those of the logged in student, so they are never added to the calendar of another target
+	if o.Assignments && o.Target.IsSelf() {
+		assignmentResult, err := syncAssignments(l, c, o)
+		result.Add(assignmentResult)
 		if err != nil {
-			log.Fatalf("Could not update Google Calendar: %v\n", err)
+			updateErr = errors.Join(updateErr, err)
 		}
-	},
+	}
+
+	err = l.SaveSession()
+	if err != nil {
+		l.Logger.Printf("Could not save Lectio session: %v\n", err)
+	}
+	return result, updateErr
 }
 
-func init() {
-	rootCmd.AddCommand(syncCmd)
+// Fills the modules with the full homework or notes of their activity pages. Activities are cached per user in the lectigo config directory
+func fillActivities(l *lectigo.Lectio, modules map[string]lectigo.Module, o syncOptions) {
+	var cache *lectigo.ActivityCache
+	dir, err := util.ConfigDir()
+	if err == nil {
+		path := filepath.Join(dir, "activities", l.LoginInfo.SchoolID+"-"+l.LoginInfo.Username+".json")
+		cache, err = lectigo.LoadActivityCache(path, activityCacheAge)
+	}
+	if err != nil {
+		l.Logger.Printf("Could not open activity cache, fetching all activities: %v\n", err)
+	}
+
+	l.FillActivities(modules, lectigo.ActivityOptions{Homework: o.FullHomework, Notes: o.Notes, Cache: cache})
 
-	syncCmd.Flags().StringP("username", "u", "", "Lectio username (required)")
-	syncCmd.Flags().StringP("password", "p", "", "Lectio password (required)")
-	syncCmd.Flags().StringP("schoolID", "s", "", "Lectio school ID (required)")
-	syncCmd.Flags().IntP("weeks", "w", 2, "Amount of weeks to sync")
-	syncCmd.Flags().StringP("calendarID", "c", "primary", "Google Calendar calendar ID")
-	syncCmd.Flags().StringP("tokenPath", "t", "token.json", "The path to a Google OAuth token file")
+	if cache != nil {
+		if err := cache.Save(); err != nil {
+			l.Logger.Printf("Could not save activity cache: %v\n", err)
+		}
+	}
+}
 
-	syncCmd.MarkFlagRequired("username")
-	syncCmd.MarkFlagRequired("password")
-	syncCmd.MarkFlagRequired("schoolID")
+// Syncs the assignment deadlines of the coming weeks to Google Calendar
+func syncAssignments(l *lectigo.Lectio, c *lectigo.GoogleCalendar, o syncOptions) (*lectigo.SyncResult, error) {
+	assignments, err := l.GetAssignments()
+	if err != nil {
+		return nil, fmt.Errorf("Could not get Lectio assignments: %w", err)
+	}
+	result, err := c.SyncAssignments(assignments, o.Weeks, o.AssignmentsAllDay)
+	if err != nil {
+		return result, fmt.Errorf("Could not update assignments in Google Calendar: %v", err)
+	}
+	return result, nil
 }
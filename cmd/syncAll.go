@@ -0,0 +1,366 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/util"
+	"github.com/spf13/cobra"
+)
+
+// The amount of users synced at the same time, if neither the roster nor --parallel sets it
+const defaultRosterConcurrency = 4
+
+// A list of users synced by sync-all (YAML, or TOML if the file ends in .toml). Relative paths in it are relative to the roster file
+type Roster struct {
+	Concurrency int            `yaml:"concurrency" toml:"concurrency"` // The amount of users synced at the same time
+	Weeks       int            `yaml:"weeks" toml:"weeks"`             // Amount of weeks to sync, unless set for the user
+	Timezone    string         `yaml:"timezone" toml:"timezone"`       // The timezone of the schools, unless set for the user
+	Calendar    RosterCalendar `yaml:"calendar" toml:"calendar"`       // Calendar settings shared by all users. The settings of each user override them
+
+	Assignments       bool `yaml:"assignments" toml:"assignments"`             // Also sync the deadlines of assignments
+	AssignmentsAllDay bool `yaml:"assignmentsAllDay" toml:"assignmentsAllDay"` // Sync assignment deadlines as all-day events
+	FullHomework      bool `yaml:"fullHomework" toml:"fullHomework"`           // Get the full homework of modules from their activity pages
+	Notes             bool `yaml:"notes" toml:"notes"`                         // Get the note and other content of modules from their activity pages
+	DayEvents         bool `yaml:"dayEvents" toml:"dayEvents"`                 // Also sync day events (eg. excursions) as all-day events
+
+	Users []RosterUser `yaml:"users" toml:"users"`
+}
+
+// A user of a roster
+type RosterUser struct {
+	Name        string         `yaml:"name" toml:"name"`               // The name of the user in the report. Defaults to the username
+	SchoolID    string         `yaml:"schoolID" toml:"schoolID"`       // Lectio school ID
+	Username    string         `yaml:"username" toml:"username"`       // Lectio username
+	Password    string         `yaml:"password" toml:"password"`       // Lectio password. Prefer passwordEnv, the keyring (lectigo login) or a saved session
+	PasswordEnv string         `yaml:"passwordEnv" toml:"passwordEnv"` // The environment variable holding the Lectio password
+	Session     string         `yaml:"session" toml:"session"`         // The path to the encrypted Lectio session file of the user
+	Weeks       int            `yaml:"weeks" toml:"weeks"`
+	Timezone    string         `yaml:"timezone" toml:"timezone"`
+	Calendar    RosterCalendar `yaml:"calendar" toml:"calendar"`
+	Teacher     string         `yaml:"teacher" toml:"teacher"` // Sync the schedule of the teacher with this Lectio ID instead of the user's own
+	Room        string         `yaml:"room" toml:"room"`       // Sync the booking schedule of the room with this Lectio ID instead of the user's own
+	Class       string         `yaml:"class" toml:"class"`     // Sync the schedule of the class with this Lectio ID instead of the user's own
+}
+
+// The calendar a roster user is synced to
+type RosterCalendar struct {
+	Backend         string   `yaml:"backend" toml:"backend"`                 // The calendar backend. Only "google" is supported
+	ID              string   `yaml:"id" toml:"id"`                           // The calendar ID
+	CredentialsPath string   `yaml:"credentialsPath" toml:"credentialsPath"` // The path to the Google OAuth client credentials file
+	TokenPath       string   `yaml:"tokenPath" toml:"tokenPath"`             // The path to the Google OAuth token file
+	Scopes          []string `yaml:"scopes" toml:"scopes"`                   // The OAuth scopes to request access to
+	ServiceAccount  string   `yaml:"serviceAccount" toml:"serviceAccount"`   // The path to a Google service account key file
+	Impersonate     string   `yaml:"impersonate" toml:"impersonate"`         // The email of the user the service account acts as
+}
+
+// The outcome of syncing a roster user
+type RosterReport struct {
+	Name   string              `json:"name"`
+	Result *lectigo.SyncResult `json:"result,omitempty"` // Nil if the calendar was never updated
+	Error  string              `json:"error,omitempty"`
+}
+
+// syncAllCmd represents the sync-all command
+var syncAllCmd = &cobra.Command{
+	Use:   "sync-all",
+	Short: "Syncs the Lectio schedules of all users in a roster file",
+	Long: `Syncs the Lectio schedules of all users in a roster file with their Google Calendars, several at a time, and reports the outcome for each user.
+
+Each user is logged in to Lectio separately, with a session file of their own. A missing password is looked up in the keyring, as saved by "lectigo login". Without one, the saved session of the user is used.
+The filters and templates of the config file apply to all users.
+
+Example roster:
+
+  concurrency: 4
+  weeks: 2
+  calendar:
+    serviceAccount: key.json
+  users:
+    - name: anna
+      schoolID: "133"
+      username: anna1234
+      passwordEnv: ANNA_PASSWORD
+      calendar:
+        impersonate: anna@school.dk
+    - schoolID: "133"
+      username: bo5678
+      calendar:
+        id: somecalendarid1234@group.calendar.google.com
+        tokenPath: tokens/bo.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		rosterPath, _ := cmd.Flags().GetString("roster")
+		parallel, _ := cmd.Flags().GetInt("parallel")
+		format, _ := cmd.Flags().GetString("format")
+
+		if format != "table" && format != "json" {
+			log.Fatalf("Unknown report format %s. Use table or json\n", format)
+		}
+
+		roster, err := loadRoster(rosterPath)
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+		if parallel <= 0 {
+			parallel = roster.Concurrency
+		}
+		if parallel <= 0 {
+			parallel = defaultRosterConcurrency
+		}
+
+		filter, err := config.moduleFilter()
+		if err != nil {
+			log.Fatalf("Could not create module filter from config: %v\n", err)
+		}
+		template, err := config.eventTemplate()
+		if err != nil {
+			log.Fatalf("Could not parse event templates from config: %v\n", err)
+		}
+		// The keyrings are shared by all users, so the file keyring is not created by several users at once
+		keyringKind, _ := cmd.Flags().GetString("keyring")
+		keyrings, err := openKeyrings(keyringKind)
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+
+		reports := make([]RosterReport, len(roster.Users))
+		sem := make(chan struct{}, parallel)
+		var wg sync.WaitGroup
+		for i, user := range roster.Users {
+			wg.Add(1)
+			go func(i int, user RosterUser) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				result, err := syncRosterUser(cmd, roster, user, keyrings, filter, template)
+				reports[i] = RosterReport{Name: user.name(), Result: result}
+				if err != nil {
+					reports[i].Error = err.Error()
+				}
+			}(i, user)
+		}
+		wg.Wait()
+
+		err = writeRosterReport(os.Stdout, format, reports)
+		if err != nil {
+			log.Fatalf("%v\n", err)
+		}
+		for _, report := range reports {
+			if report.Error != "" {
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncAllCmd)
+
+	syncAllCmd.Flags().String("roster", "", "The path to the roster file listing the users to sync")
+	syncAllCmd.Flags().Int("parallel", 0, fmt.Sprintf("The amount of users synced at the same time (default is the roster concurrency, or %d)", defaultRosterConcurrency))
+	syncAllCmd.Flags().String("format", "table", "The format of the report (table or json)")
+	syncAllCmd.Flags().String("keyring", "auto", "The keyring to look up Lectio passwords in (auto, system or file)")
+	syncAllCmd.Flags().String("lectio-url", lectigo.DefaultLectioBaseURL, "The base URL of Lectio")
+	syncAllCmd.Flags().MarkHidden("lectio-url")
+	syncAllCmd.Flags().String("calendar-url", "", "The base URL of the Google Calendar API")
+	syncAllCmd.Flags().MarkHidden("calendar-url")
+	syncAllCmd.MarkFlagRequired("roster")
+}
+
+// Reads a roster file. Relative paths in the roster are made relative to the directory of the file
+func loadRoster(path string) (*Roster, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read roster file: %v", err)
+	}
+
+	roster := &Roster{}
+	err = unmarshalFile(path, b, roster)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse roster file %s: %v", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	roster.Calendar.resolvePaths(dir)
+	for i := range roster.Users {
+		user := &roster.Users[i]
+		if user.SchoolID == "" || user.Username == "" {
+			return nil, fmt.Errorf("User %d of roster file %s has no schoolID or username", i+1, path)
+		}
+		user.Session = resolvePath(dir, user.Session)
+		user.Calendar.resolvePaths(dir)
+	}
+	return roster, nil
+}
+
+// Returns the path relative to the directory, unless it is empty or absolute
+func resolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func (c *RosterCalendar) resolvePaths(dir string) {
+	c.CredentialsPath = resolvePath(dir, c.CredentialsPath)
+	c.TokenPath = resolvePath(dir, c.TokenPath)
+	c.ServiceAccount = resolvePath(dir, c.ServiceAccount)
+}
+
+// Returns the name of the user in the report
+func (u *RosterUser) name() string {
+	return firstNonEmpty(u.Name, u.Username)
+}
+
+// Returns the Google Calendar settings of the user, using the shared settings of the roster for those the user does not set
+func (u *RosterUser) googleSettings(shared RosterCalendar) (*googleSettings, error) {
+	c := u.Calendar
+	backend := firstNonEmpty(c.Backend, shared.Backend, "google")
+	if backend != "google" {
+		return nil, fmt.Errorf("Unknown calendar backend %q. Only google is supported", backend)
+	}
+
+	scopes := c.Scopes
+	if len(scopes) == 0 {
+		scopes = shared.Scopes
+	}
+	return &googleSettings{
+		CalendarID:      firstNonEmpty(c.ID, shared.ID, "primary"),
+		CredentialsPath: firstNonEmpty(c.CredentialsPath, shared.CredentialsPath),
+		TokenPath:       firstNonEmpty(c.TokenPath, shared.TokenPath),
+		Scopes:          scopes,
+		ServiceAccount:  firstNonEmpty(c.ServiceAccount, shared.ServiceAccount),
+		Impersonate:     firstNonEmpty(c.Impersonate, shared.Impersonate),
+	}, nil
+}
+
+// Returns the Lectio login information of the user. A missing password is looked up in the keyring, and left empty if not found there
+func (u *RosterUser) loginInfo(keyrings []util.Keyring) *lectigo.LectioLoginInfo {
+	password := u.Password
+	if u.PasswordEnv != "" {
+		password = firstNonEmpty(password, os.Getenv(u.PasswordEnv))
+	}
+	if password == "" {
+		password, _ = keyringGet(keyrings, keyringAccount(u.SchoolID, u.Username))
+	}
+	return &lectigo.LectioLoginInfo{
+		Username: u.Username,
+		Password: password,
+		SchoolID: u.SchoolID,
+	}
+}
+
+// Returns the path of the Lectio session file of the user, defaulting to a file per user in the lectigo config directory
+func (u *RosterUser) sessionPath() (string, error) {
+	if u.Session != "" {
+		return u.Session, nil
+	}
+	dir, err := util.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sessions", u.SchoolID+"-"+u.Username), nil
+}
+
+// Syncs the schedule of a roster user with their own Lectio session and calendar. A missing password is looked up in the keyrings
+func syncRosterUser(cmd *cobra.Command, roster *Roster, user RosterUser, keyrings []util.Keyring, filter *lectigo.ModuleFilter, template *lectigo.EventTemplate) (*lectigo.SyncResult, error) {
+	baseURL, _ := cmd.Flags().GetString("lectio-url")
+	calendarURL, _ := cmd.Flags().GetString("calendar-url")
+
+	weeks := user.Weeks
+	if weeks <= 0 {
+		weeks = roster.Weeks
+	}
+	if weeks <= 0 {
+		weeks = 2
+	}
+	target, err := scheduleTarget(user.Teacher, user.Room, user.Class)
+	if err != nil {
+		return nil, err
+	}
+	timezone := firstNonEmpty(user.Timezone, roster.Timezone, util.DefaultTimezone)
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("Could not load timezone %q: %v", timezone, err)
+	}
+
+	settings, err := user.googleSettings(roster.Calendar)
+	if err != nil {
+		return nil, err
+	}
+	settings.Endpoint = calendarURL
+
+	logger := log.New(os.Stdout, fmt.Sprintf("[%s] ", user.name()), log.LstdFlags)
+	c, err := settings.newCalendar(lectigo.WithLocation(location), lectigo.WithEventTemplate(template), lectigo.WithLogger(logger))
+	if err != nil {
+		return nil, err
+	}
+	sessionPath, err := user.sessionPath()
+	if err != nil {
+		return nil, err
+	}
+	store, err := openSessionStore(sessionPath)
+	if err != nil {
+		return nil, fmt.Errorf("Could not open Lectio session file: %v", err)
+	}
+	l, err := openLectio(user.loginInfo(keyrings),
+		lectigo.WithLocation(location),
+		lectigo.WithBaseURL(baseURL),
+		lectigo.WithSession(store),
+		lectigo.WithLogger(logger),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return syncSchedule(l, c, syncOptions{
+		Weeks:             weeks,
+		Filter:            filter,
+		Assignments:       roster.Assignments || config.Assignments.Sync,
+		AssignmentsAllDay: roster.AssignmentsAllDay || config.Assignments.AllDay,
+		FullHomework:      roster.FullHomework || config.FullHomework,
+		Notes:             roster.Notes || config.Notes,
+		DayEvents:         roster.DayEvents || config.DayEvents,
+		Target:            target,
+	})
+}
+
+// Writes the reports of the roster users as a table or as JSON
+func writeRosterReport(f *os.File, format string, reports []RosterReport) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(reports)
+	case "table":
+		failed := 0
+		w := tabwriter.NewWriter(f, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "USER\tSTATUS\tINSERTED\tUPDATED\tDELETED\tFAILED\tDURATION\tERROR")
+		for _, r := range reports {
+			status := "ok"
+			if r.Error != "" {
+				status = "failed"
+				failed++
+			}
+			result := r.Result
+			if result == nil {
+				result = &lectigo.SyncResult{}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%v\t%s\n", r.Name, status, result.Inserted, result.Updated, result.Deleted, result.Failed, result.Duration.Round(time.Millisecond), r.Error)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(f, "\nSynced %d of %d users\n", len(reports)-failed, len(reports))
+		return nil
+	}
+	return fmt.Errorf("Unknown report format %s. Use table or json", format)
+}
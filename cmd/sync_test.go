@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/pkg/lectigo/googlecalendartest"
+	"github.com/mattismoel/lectigo/pkg/lectigo/lectiotest"
+	"github.com/mattismoel/lectigo/util"
+	"github.com/spf13/cobra"
+)
+
+// A fake Lectio and Google Calendar, and a Lectio and GoogleCalendar connected to them at a fixed time
+type syncFixture struct {
+	lectioServer   *lectiotest.Server
+	calendarServer *googlecalendartest.Server
+	clock          *util.FakeClock
+	lectio         *lectigo.Lectio
+	calendar       *lectigo.GoogleCalendar
+}
+
+// Logs in to a fake Lectio and connects to a fake Google Calendar, with the clock set to the input time in Europe/Copenhagen
+func newSyncFixture(t *testing.T, year int, month time.Month, day int) *syncFixture {
+	t.Helper()
+	location, err := time.LoadLocation(util.DefaultTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &syncFixture{
+		lectioServer:   lectiotest.NewServer(),
+		calendarServer: googlecalendartest.NewServer(),
+		clock:          util.NewFakeClock(time.Date(year, month, day, 12, 0, 0, 0, location)),
+	}
+	t.Cleanup(f.lectioServer.Close)
+	t.Cleanup(f.calendarServer.Close)
+
+	opts := []lectigo.Option{
+		lectigo.WithClock(f.clock),
+		lectigo.WithLocation(location),
+		lectigo.WithLogger(log.New(io.Discard, "", 0)),
+	}
+	f.lectio, err = lectigo.NewLectio(
+		&lectigo.LectioLoginInfo{Username: f.lectioServer.Username, Password: f.lectioServer.Password, SchoolID: f.lectioServer.SchoolID},
+		append(opts, lectigo.WithBaseURL(f.lectioServer.BaseURL()))...,
+	)
+	if err != nil {
+		t.Fatalf("Could not log in to the fake Lectio: %v", err)
+	}
+	f.calendar, err = f.calendarServer.NewGoogleCalendar("primary", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// Returns the IDs of the events of the prefix in the fake calendar that are not deleted
+func (f *syncFixture) events(prefix string) []string {
+	var ids []string
+	for _, event := range f.calendarServer.Events("primary") {
+		if strings.HasPrefix(event.Id, prefix) && event.Status != "cancelled" {
+			ids = append(ids, event.Id)
+		}
+	}
+	return ids
+}
+
+func TestSyncSchedule(t *testing.T) {
+	f := newSyncFixture(t, 2024, time.December, 11)
+	o := syncOptions{Weeks: 2}
+
+	result, err := syncSchedule(f.lectio, f.calendar, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modules, err := f.lectio.GetScheduleWeeks(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modules) == 0 {
+		t.Fatal("The fake Lectio has no modules in the synced weeks")
+	}
+	if result.Inserted != len(modules) || result.Updated != 0 || result.Deleted != 0 || result.Failed != 0 {
+		t.Errorf("First sync inserted %d, updated %d, deleted %d and failed %d events, want %d inserted", result.Inserted, result.Updated, result.Deleted, result.Failed, len(modules))
+	}
+	events := f.events(lectigo.ModuleEventPrefix)
+	if len(events) != len(modules) {
+		t.Errorf("Calendar has %d module events, want %d", len(events), len(modules))
+	}
+	for _, id := range events {
+		if _, ok := modules[strings.TrimPrefix(id, lectigo.ModuleEventPrefix)]; !ok {
+			t.Errorf("Calendar has event %s of no module", id)
+		}
+	}
+
+	// Nothing has changed in Lectio, so a second sync should not touch the calendar
+	result, err = syncSchedule(f.lectio, f.calendar, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inserted != 0 || result.Updated != 0 || result.Deleted != 0 || result.Failed != 0 {
+		t.Errorf("Second sync inserted %d, updated %d, deleted %d and failed %d events, want no changes", result.Inserted, result.Updated, result.Deleted, result.Failed)
+	}
+
+	// A week later the first week is no longer synced, and the week after is added
+	f.clock.Advance(7 * 24 * time.Hour)
+	result, err = syncSchedule(f.lectio, f.calendar, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inserted == 0 || result.Failed != 0 {
+		t.Errorf("Sync a week later inserted %d and failed %d events, want the modules of the new week inserted", result.Inserted, result.Failed)
+	}
+}
+
+func TestSyncScheduleDayEventsAndAssignments(t *testing.T) {
+	f := newSyncFixture(t, 2025, time.January, 20)
+	o := syncOptions{Weeks: 2, DayEvents: true, Assignments: true}
+
+	result, err := syncSchedule(f.lectio, f.calendar, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Failed != 0 {
+		t.Errorf("Sync failed %d events", result.Failed)
+	}
+	if len(f.events(lectigo.DayEventPrefix)) == 0 {
+		t.Errorf("No day events synced from the week with day events")
+	}
+	if len(f.events(lectigo.ModuleEventPrefix)) == 0 {
+		t.Errorf("No modules synced")
+	}
+}
+
+func TestSyncScheduleLectioUnavailable(t *testing.T) {
+	f := newSyncFixture(t, 2024, time.December, 11)
+	f.lectioServer.SetUnavailable(true)
+
+	result, err := syncSchedule(f.lectio, f.calendar, syncOptions{Weeks: 2})
+	if err == nil {
+		t.Fatal("Sync succeeded while Lectio was unavailable")
+	}
+	if result != nil {
+		t.Errorf("Got a result of a sync that did not reach the calendar: %+v", result)
+	}
+	if n := len(f.calendarServer.Events("primary")); n != 0 {
+		t.Errorf("Calendar has %d events after a failed sync, want none", n)
+	}
+}
+
+func TestSyncScheduleCalendarErrors(t *testing.T) {
+	f := newSyncFixture(t, 2024, time.December, 11)
+	f.calendarServer.Fail(googlecalendartest.Method(http.MethodPost), http.StatusInternalServerError, 1)
+
+	result, err := syncSchedule(f.lectio, f.calendar, syncOptions{Weeks: 2})
+	if err == nil {
+		t.Fatal("Sync succeeded although an insert failed")
+	}
+	if result == nil || result.Failed != 1 || result.Inserted == 0 {
+		t.Fatalf("Got result %+v, want one failed insert and the other events inserted", result)
+	}
+
+	// The failed event is inserted by the next sync
+	result, err = syncSchedule(f.lectio, f.calendar, syncOptions{Weeks: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inserted != 1 {
+		t.Errorf("Next sync inserted %d events, want the 1 that failed", result.Inserted)
+	}
+}
+
+// The assignments are those of the logged in student, so they are not synced to the calendar of a teacher
+func TestSyncScheduleTargetSkipsAssignments(t *testing.T) {
+	f := newSyncFixture(t, 2024, time.December, 11)
+	o := syncOptions{Weeks: 2, Assignments: true, Target: lectigo.ScheduleTarget{Kind: lectigo.TargetTeacher, Id: "7000000001"}}
+
+	if _, err := syncSchedule(f.lectio, f.calendar, o); err != nil {
+		t.Fatal(err)
+	}
+	if events := f.events(lectigo.AssignmentEventPrefix); len(events) != 0 {
+		t.Errorf("Synced the assignments %v of the student to the schedule of a teacher", events)
+	}
+	if len(f.events(lectigo.ModuleEventPrefix)) == 0 {
+		t.Errorf("No modules of the teacher synced")
+	}
+}
+
+func TestSyncOptionsTargetAssignments(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		config      bool // Assignments turned on in the config
+		assignments bool
+		wantErr     bool
+	}{
+		{"own schedule", []string{"--assignments"}, false, true, false},
+		{"teacher with --assignments", []string{"--teacher", "7000000001", "--assignments"}, false, false, true},
+		{"teacher with assignments in the config", []string{"--teacher", "7000000001"}, true, false, false},
+		{"own schedule with assignments in the config", nil, true, true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			addSyncFlags(cmd)
+			if err := cmd.Flags().Parse(test.args); err != nil {
+				t.Fatal(err)
+			}
+			c := &Config{}
+			c.Assignments.Sync = test.config
+			if err := c.applyToFlags(cmd); err != nil {
+				t.Fatal(err)
+			}
+
+			o, err := syncOptionsFromFlags(cmd)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Got error %v, want an error: %v", err, test.wantErr)
+			}
+			if err == nil && o.Assignments != test.assignments {
+				t.Errorf("Got assignments %v, want %v", o.Assignments, test.assignments)
+			}
+		})
+	}
+}
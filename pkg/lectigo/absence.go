@@ -0,0 +1,152 @@
+package lectigo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Returned when a page does not contain the Lectio absence table
+var ErrAbsenceNotFound = errors.New("Could not find an absence table in the Lectio page")
+
+// The title of the row of the absence table summing up all teams
+const absenceTotalRow = "Samlet"
+
+// An amount of absence, as a percentage and as the absent part of a total (eg. 2 of 38 modules)
+type AbsenceCount struct {
+	Percent float64 `json:"percent"` // The absence in percent (eg. 5.26)
+	Absent  float64 `json:"absent"`  // The absent modules or student hours
+	Total   float64 `json:"total"`   // All modules or student hours
+}
+
+// The absence of a student in a team (hold)
+type TeamAbsence struct {
+	Team        string       `json:"team"`        // The team (eg. "3a Dansk")
+	Modules     AbsenceCount `json:"modules"`     // Absence from modules held so far (opgjort)
+	ModulesYear AbsenceCount `json:"modulesYear"` // Absence from modules out of all modules of the year
+	Written     AbsenceCount `json:"written"`     // Absence from written assignments handed out so far, in student hours
+	WrittenYear AbsenceCount `json:"writtenYear"` // Absence from written assignments out of all assignments of the year
+}
+
+// The absence overview of a student
+type Absence struct {
+	Teams []TeamAbsence `json:"teams"` // The absence of each team, in the order Lectio shows them
+	Total TeamAbsence   `json:"total"` // The absence of all teams together
+}
+
+// Reports whether the absence from modules or written assignments held so far is at least the threshold, in percent
+func (a *TeamAbsence) Exceeds(threshold float64) bool {
+	return a.Modules.Percent >= threshold || a.Written.Percent >= threshold
+}
+
+// Parses a Lectio absence overview page (fravaerelev.aspx).
+// Problems with single rows are returned as warnings, while an error is returned if the absence table itself cannot be read
+func ParseAbsence(r io.Reader) (*Absence, []string, error) {
+	document, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	table := document.Find("table[id$='AbsenceDataTable']").First()
+	if table.Length() == 0 {
+		return nil, nil, ErrAbsenceNotFound
+	}
+
+	absence := &Absence{}
+	var warnings []string
+	table.Find("tr").Each(func(row int, tr *goquery.Selection) {
+		cells := tr.Find("td")
+		if cells.Length() == 0 {
+			return
+		}
+		if cells.Length() < 9 {
+			warnings = append(warnings, fmt.Sprintf("row %d: expected 9 columns, got %d", row, cells.Length()))
+			return
+		}
+
+		team := TeamAbsence{Team: cellText(cells.Eq(0))}
+		counts := []*AbsenceCount{&team.Modules, &team.ModulesYear, &team.Written, &team.WrittenYear}
+		for i, count := range counts {
+			c, err := parseAbsenceCount(cellText(cells.Eq(1+2*i)), cellText(cells.Eq(2+2*i)))
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("row %d (%s): %v", row, team.Team, err))
+				continue
+			}
+			*count = c
+		}
+
+		if team.Team == absenceTotalRow {
+			absence.Total = team
+			return
+		}
+		absence.Teams = append(absence.Teams, team)
+	})
+
+	return absence, warnings, nil
+}
+
+// Parses a Lectio percentage (eg. "5,26%") and fraction (eg. "2/38" or "2,5/38"). Empty cells are no absence
+func parseAbsenceCount(percent, fraction string) (AbsenceCount, error) {
+	c := AbsenceCount{}
+	if percent != "" {
+		p, err := parseDanishFloat(strings.TrimSpace(strings.TrimSuffix(percent, "%")))
+		if err != nil {
+			return c, fmt.Errorf("Invalid percentage %q", percent)
+		}
+		c.Percent = p
+	}
+	if fraction != "" {
+		absent, total, ok := strings.Cut(fraction, "/")
+		if !ok {
+			return c, fmt.Errorf("Invalid fraction %q", fraction)
+		}
+		var err error
+		c.Absent, err = parseDanishFloat(absent)
+		if err != nil {
+			return c, fmt.Errorf("Invalid fraction %q", fraction)
+		}
+		c.Total, err = parseDanishFloat(total)
+		if err != nil {
+			return c, fmt.Errorf("Invalid fraction %q", fraction)
+		}
+	}
+	return c, nil
+}
+
+// Parses a number with a decimal comma (eg. "2,5")
+func parseDanishFloat(s string) (float64, error) {
+	return strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", "."), 64)
+}
+
+// Gets the absence overview of the logged in student
+func (l *Lectio) GetAbsence() (*Absence, error) {
+	studentID, err := l.StudentID()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := l.Client.Get(l.pageURL("subnav/fravaerelev.aspx?elevid=" + url.QueryEscape(studentID)))
+	if err != nil {
+		return nil, requestError(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Could not get Lectio absence: %w", statusError(response))
+	}
+
+	absence, warnings, err := ParseAbsence(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse Lectio absence: %w", err)
+	}
+	for _, warning := range warnings {
+		l.Logger.Printf("Lectio absence: %s\n", warning)
+	}
+	return absence, nil
+}
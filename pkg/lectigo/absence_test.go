@@ -0,0 +1,50 @@
+package lectigo_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+)
+
+func TestParseAbsence(t *testing.T) {
+	absence, warnings, err := lectigo.ParseAbsence(bytes.NewReader(readTestdata(t, "absence", "fravaer.html")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Got warnings %q of the saved page", warnings)
+	}
+	checkGolden(t, absence, "absence", "fravaer.golden.json")
+}
+
+// An absence table with a valid team between a team with a bad percentage and a row with too few columns
+const badAbsenceRows = `<table id="s_m_Content_Content_SFTabStudentAbsenceDataTable">
+<tr><td>3a Dansk</td><td>mange</td><td>2/38</td><td>3,33%</td><td>2/60</td><td>0,00%</td><td>0/12</td><td>0,00%</td><td>0/20</td></tr>
+<tr><td>3a Engelsk</td><td>12,50%</td><td>4/32</td><td>7,14%</td><td>4/56</td><td>0,00%</td><td>0/12</td><td>0,00%</td><td>0/20</td></tr>
+<tr><td>3a Fysik</td><td>8,57%</td></tr>
+</table>`
+
+func TestParseAbsenceBadRows(t *testing.T) {
+	absence, warnings, err := lectigo.ParseAbsence(strings.NewReader(badAbsenceRows))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The team with a bad percentage is kept with the counts that could be read, so a threshold still finds the other teams
+	if len(absence.Teams) != 2 || absence.Teams[0].ModulesYear.Percent != 3.33 || !absence.Teams[1].Exceeds(10) {
+		t.Errorf("Got teams %+v, want 3a Dansk and 3a Engelsk above 10 %%", absence.Teams)
+	}
+	if len(warnings) != 2 || !strings.HasPrefix(warnings[0], "row 0 (3a Dansk):") || !strings.HasPrefix(warnings[1], "row 2:") {
+		t.Errorf("Got warnings %q, want one for row 0 and one for row 2", warnings)
+	}
+}
+
+func TestParseAbsenceNotFound(t *testing.T) {
+	_, _, err := lectigo.ParseAbsence(strings.NewReader("<html><body><p>Log ind</p></body></html>"))
+	if !errors.Is(err, lectigo.ErrAbsenceNotFound) {
+		t.Errorf("Got error %v, want %v", err, lectigo.ErrAbsenceNotFound)
+	}
+}
@@ -0,0 +1,269 @@
+package lectigo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Returned when a page is not a Lectio activity page, for example if the session is logged out
+var ErrActivityNotFound = errors.New("Could not find an activity in the Lectio page")
+
+// The amount of activity pages fetched at the same time by FillActivities
+const activityConcurrency = 4
+
+// A link in the homework of a module
+type Link struct {
+	Title    string `json:"title"`              // The text of the link, or the URL if it has none
+	URL      string `json:"url"`                // The URL of the link. Absolute when returned by GetActivity
+	Document bool   `json:"document,omitempty"` // Whether the link is a document attached in Lectio
+}
+
+// Returns the link as a line of a list (eg. "- Sneglen.pdf: https://...")
+func (l Link) String() string {
+	if l.Title == l.URL {
+		return "- " + l.URL
+	}
+	return fmt.Sprintf("- %s: %s", l.Title, l.URL)
+}
+
+// The activity page (aktivitetforside2.aspx) of a module
+type Activity struct {
+	Homework string `json:"homework"` // The full homework of the module as text
+	Links    []Link `json:"links"`    // The links and documents in the homework
+	Note     string `json:"note"`     // The note of the module as text
+	Content  string `json:"content"`  // The other content (Øvrigt indhold) of the module as text
+}
+
+// Parses a Lectio activity page (aktivitet/aktivitetforside2.aspx). Sections the activity does not have are empty
+func ParseActivity(r io.Reader) (*Activity, error) {
+	document, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if document.Find("[id$='ActivityHeader']").Length() == 0 {
+		return nil, ErrActivityNotFound
+	}
+
+	homework := document.Find("[id$='inlineHomeworkDiv']").First()
+	return &Activity{
+		Homework: htmlText(homework),
+		Links:    parseLinks(homework),
+		Note:     htmlText(document.Find("[id$='inlineNoteDiv']").First()),
+		Content:  htmlText(document.Find("[id$='inlineContentDiv']").First()),
+	}, nil
+}
+
+// Returns the links of the selection, leaving out links that run scripts
+func parseLinks(s *goquery.Selection) []Link {
+	var links []Link
+	s.Find("a[href]").Each(func(i int, a *goquery.Selection) {
+		if isScriptLink(a) {
+			return
+		}
+		href, _ := a.Attr("href")
+		href = strings.TrimSpace(href)
+		title := cellText(a)
+		if title == "" {
+			title = href
+		}
+		links = append(links, Link{
+			Title:    title,
+			URL:      href,
+			Document: strings.Contains(strings.ToLower(href), "dokumenthent.aspx"),
+		})
+	})
+	return links
+}
+
+// Reports whether the link is a control of the page (eg. "Vis mere") rather than a link to something
+func isScriptLink(a *goquery.Selection) bool {
+	href, _ := a.Attr("href")
+	href = strings.TrimSpace(href)
+	return href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:")
+}
+
+// Elements shown as paragraphs, which are separated from the text around them by an empty line
+var paragraphElements = map[string]bool{
+	"p": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "table": true, "blockquote": true, "pre": true,
+}
+
+// Elements shown on lines of their own
+var lineElements = map[string]bool{
+	"div": true, "article": true, "section": true, "header": true, "footer": true, "tr": true, "hr": true,
+}
+
+// Returns the text of HTML as it is shown: paragraphs are separated by empty lines, line breaks are kept and list items are put on lines starting with "-" or their number
+func htmlText(s *goquery.Selection) string {
+	w := &textWriter{}
+	w.writeChildren(s)
+	return w.sb.String()
+}
+
+// Writes text while collapsing whitespace, and only adds line breaks and spaces before text that follows them
+type textWriter struct {
+	sb       strings.Builder
+	space    bool // Whether a space is to be written before the next text
+	newlines int  // The amount of line breaks to write before the next text
+}
+
+func (w *textWriter) writeChildren(s *goquery.Selection) {
+	var item int
+	s.Contents().Each(func(i int, child *goquery.Selection) {
+		name := goquery.NodeName(child)
+		switch {
+		case name == "#text":
+			w.text(child.Text())
+		case name == "br":
+			w.newlines++
+			w.space = false
+		case name == "li":
+			item++
+			w.lineBreak(1)
+			if goquery.NodeName(s) == "ol" {
+				w.text(strconv.Itoa(item) + ". ")
+			} else {
+				w.text("- ")
+			}
+			w.writeChildren(child)
+			w.lineBreak(1)
+		case name == "script" || name == "style" || (name == "a" && isScriptLink(child)):
+		case paragraphElements[name]:
+			w.lineBreak(2)
+			w.writeChildren(child)
+			w.lineBreak(2)
+		case lineElements[name]:
+			w.lineBreak(1)
+			w.writeChildren(child)
+			w.lineBreak(1)
+		default:
+			w.writeChildren(child)
+		}
+	})
+}
+
+// Adds at least n line breaks before the next text
+func (w *textWriter) lineBreak(n int) {
+	w.newlines = max(w.newlines, n)
+	w.space = false
+}
+
+func (w *textWriter) text(s string) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s != "" {
+			w.space = true
+		}
+		return
+	}
+
+	if w.sb.Len() > 0 {
+		if w.newlines > 0 {
+			w.sb.WriteString(strings.Repeat("\n", min(w.newlines, 2)))
+		} else if w.space || strings.TrimLeftFunc(s, unicode.IsSpace) != s {
+			w.sb.WriteByte(' ')
+		}
+	}
+	w.sb.WriteString(strings.Join(fields, " "))
+	w.newlines = 0
+	w.space = strings.TrimRightFunc(s, unicode.IsSpace) != s
+}
+
+// Gets the activity page of a module. Links are made absolute
+func (l *Lectio) GetActivity(moduleID string) (*Activity, error) {
+	response, err := l.Client.Get(l.pageURL("aktivitet/aktivitetforside2.aspx?absid=" + url.QueryEscape(moduleID)))
+	if err != nil {
+		return nil, requestError(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Could not get Lectio activity %v: %w", moduleID, statusError(response))
+	}
+
+	activity, err := ParseActivity(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse Lectio activity %v: %v", moduleID, err)
+	}
+	for i, link := range activity.Links {
+		if u, err := response.Request.URL.Parse(link.URL); err == nil {
+			activity.Links[i].URL = u.String()
+		}
+	}
+	return activity, nil
+}
+
+// What FillActivities reads from the activity pages of modules
+type ActivityOptions struct {
+	Homework bool           // Replace the shortened homework of the schedule with the full homework and its links. Modules without homework are not fetched for it
+	Notes    bool           // Fill the note and other content of the modules. All modules are fetched for them
+	Cache    *ActivityCache // Reuses the activities of modules that have not changed. Nil fetches every activity
+}
+
+// Fills the modules with what the options ask for from their activity pages. Private appointments have no activity page and are skipped.
+// Modules whose activity cannot be fetched keep what the schedule shows, and the problem is logged
+func (l *Lectio) FillActivities(modules map[string]Module, o ActivityOptions) {
+	// The modules are copied before fetching, as the map is written while the fetches run
+	var pending []Module
+	for _, module := range modules {
+		if module.activity && (o.Notes || (o.Homework && module.Homework != "")) {
+			pending = append(pending, module)
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, activityConcurrency)
+	for _, module := range pending {
+		wg.Add(1)
+		go func(module Module) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			activity, err := l.activity(&module, o.Cache)
+			if err != nil {
+				l.Logger.Printf("Could not get the activity of module %v, using the schedule: %v\n", module.Id, err)
+				return
+			}
+			if o.Homework && activity.Homework != "" {
+				module.Homework = activity.Homework
+				module.Links = activity.Links
+			}
+			if o.Notes {
+				module.Note = activity.Note
+				module.Content = activity.Content
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			modules[module.Id] = module
+		}(module)
+	}
+	wg.Wait()
+}
+
+// Returns the activity of the module from the cache, or fetches it if the cache does not have it
+func (l *Lectio) activity(m *Module, cache *ActivityCache) (*Activity, error) {
+	if cache == nil {
+		return l.GetActivity(m.Id)
+	}
+	if activity, ok := cache.get(m, l.Clock.Now()); ok {
+		return activity, nil
+	}
+	activity, err := l.GetActivity(m.Id)
+	if err != nil {
+		return nil, err
+	}
+	cache.put(m, activity, l.Clock.Now())
+	return activity, nil
+}
@@ -0,0 +1,52 @@
+package lectigo_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/pkg/lectigo/lectiotest"
+	"github.com/mattismoel/lectigo/util"
+)
+
+func TestParseActivity(t *testing.T) {
+	for _, id := range []string{"61000600", "61000602", "61000603", "61000605"} {
+		t.Run(id, func(t *testing.T) {
+			activity, err := lectigo.ParseActivity(bytes.NewReader(readTestdata(t, "activity", id+".html")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, activity, "activity", id+".golden.json")
+		})
+	}
+}
+
+// Fills the activities of a week with more modules than are fetched at a time. Run with -race to check the modules are not read while they are written
+func TestFillActivities(t *testing.T) {
+	server := lectiotest.NewServer()
+	defer server.Close()
+	l := newLectio(t, server, util.NewFakeClock(time.Date(2025, time.January, 13, 12, 0, 0, 0, copenhagen(t))))
+
+	modules, err := l.GetSchedule(util.Week{Year: 2025, Week: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := len(modules)
+	l.FillActivities(modules, lectigo.ActivityOptions{Homework: true, Notes: true})
+
+	if len(modules) != before {
+		t.Errorf("Got %d modules after filling the activities, want %d", len(modules), before)
+	}
+	want, err := lectigo.ParseActivity(bytes.NewReader(readTestdata(t, "activity", "61000600.html")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	module, ok := modules["61000600"]
+	if !ok {
+		t.Fatal("No module 61000600 in week 2025-W03")
+	}
+	if module.Homework != want.Homework || module.Note != want.Note || len(module.Links) != len(want.Links) {
+		t.Errorf("Got homework %q, note %q and %d links of 61000600, want %q, %q and %d links", module.Homework, module.Note, len(module.Links), want.Homework, want.Note, len(want.Links))
+	}
+}
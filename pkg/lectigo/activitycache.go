@@ -0,0 +1,96 @@
+package lectigo
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Caches the activity pages of modules in a file, so an activity is only fetched again when its module has changed in the schedule.
+// Lectio does not show when a module was last changed, so a fingerprint of the module in the schedule is used instead.
+// As the schedule only shows the beginning of the homework, activities are also fetched again once they are older than MaxAge
+type ActivityCache struct {
+	Path   string        // The path of the cache file
+	MaxAge time.Duration // How long an activity of an unchanged module is used. Zero uses it until the module changes
+
+	mu      sync.Mutex
+	entries map[string]cachedActivity // Cached activities by module ID
+	used    map[string]bool           // The modules looked up since the cache was loaded
+}
+
+type cachedActivity struct {
+	Version  string    `json:"version"` // The fingerprint of the module when the activity was fetched
+	Fetched  time.Time `json:"fetched"`
+	Activity Activity  `json:"activity"`
+}
+
+// Loads the activity cache saved at the path. The cache is empty if no file exists, or if the file is not a valid cache, as it is simply filled again
+func LoadActivityCache(path string, maxAge time.Duration) (*ActivityCache, error) {
+	c := &ActivityCache{
+		Path:    path,
+		MaxAge:  maxAge,
+		entries: make(map[string]cachedActivity),
+		used:    make(map[string]bool),
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &c.entries); err != nil {
+		c.entries = make(map[string]cachedActivity)
+	}
+	return c, nil
+}
+
+// Returns the cached activity of the module, if it was fetched after the last change of the module and is not too old
+func (c *ActivityCache) get(m *Module, now time.Time) (*Activity, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.used[m.Id] = true
+	entry, ok := c.entries[m.Id]
+	if !ok || entry.Version != m.version {
+		return nil, false
+	}
+	if c.MaxAge > 0 && now.Sub(entry.Fetched) > c.MaxAge {
+		return nil, false
+	}
+	activity := entry.Activity
+	return &activity, true
+}
+
+func (c *ActivityCache) put(m *Module, activity *Activity, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.used[m.Id] = true
+	c.entries[m.Id] = cachedActivity{Version: m.version, Fetched: now, Activity: *activity}
+}
+
+// Saves the cache. Only the activities of modules looked up since the cache was loaded are kept, so modules that are no longer synced are dropped
+func (c *ActivityCache) Save() error {
+	c.mu.Lock()
+	entries := make(map[string]cachedActivity)
+	for id, entry := range c.entries {
+		if c.used[id] {
+			entries[id] = entry
+		}
+	}
+	c.mu.Unlock()
+
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(c.Path, b, 0600)
+}
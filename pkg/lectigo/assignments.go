@@ -0,0 +1,273 @@
+package lectigo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"google.golang.org/api/calendar/v3"
+)
+
+// Returned when a page does not contain the Lectio assignment table
+var ErrAssignmentsNotFound = errors.New("Could not find an assignment table in the Lectio page")
+
+// An assignment (opgave) from the Lectio assignment list
+type Assignment struct {
+	Id           string    `json:"id"`           // The exercise ID of the assignment
+	Week         int       `json:"week"`         // The week the assignment is given in
+	Team         string    `json:"team"`         // The team (hold) giving the assignment (eg. "3a Dansk")
+	Title        string    `json:"title"`        // The title of the assignment
+	Deadline     time.Time `json:"deadline"`     // The time the assignment must be handed in
+	StudentHours float64   `json:"studentHours"` // The expected time to spend on the assignment, in hours (elevtid)
+	Status       string    `json:"status"`       // The status of the assignment (eg. "Afleveret", "Venter" or "Mangler")
+	Absence      string    `json:"absence"`      // The absence registered for the assignment (eg. "0 %")
+	AwaitingFrom string    `json:"awaitingFrom"` // Who the assignment awaits (eg. "Elev" or "Lærer")
+	Note         string    `json:"note"`         // The note of the teacher
+	Grade        string    `json:"grade"`        // The grade given, if any
+	StudentNote  string    `json:"studentNote"`  // The note of the student
+	URL          string    `json:"url"`          // The URL of the assignment page. Absolute when returned by GetAssignments
+}
+
+// The IDs of the assignments in Lectio links
+var exerciseIDPattern = regexp.MustCompile(`exerciseid=(\d+)`)
+
+// The columns of the Lectio assignment table by their header
+var assignmentColumns = map[string]func(a *Assignment, s *goquery.Selection, location *time.Location) error{
+	"Uge": func(a *Assignment, s *goquery.Selection, _ *time.Location) error {
+		week, err := strconv.Atoi(cellText(s))
+		if err != nil {
+			return fmt.Errorf("Invalid week %q", cellText(s))
+		}
+		a.Week = week
+		return nil
+	},
+	"Hold": func(a *Assignment, s *goquery.Selection, _ *time.Location) error {
+		a.Team = cellText(s)
+		return nil
+	},
+	"Opgavetitel": func(a *Assignment, s *goquery.Selection, _ *time.Location) error {
+		a.Title = cellText(s)
+		href, _ := s.Find("a").Attr("href")
+		a.URL = href
+		if match := exerciseIDPattern.FindStringSubmatch(href); match != nil {
+			a.Id = match[1]
+		}
+		return nil
+	},
+	"Frist": func(a *Assignment, s *goquery.Selection, location *time.Location) error {
+		deadline, err := time.ParseInLocation("2/1-2006 15:04", cellText(s), location)
+		if err != nil {
+			return fmt.Errorf("Invalid deadline %q", cellText(s))
+		}
+		a.Deadline = deadline
+		return nil
+	},
+	"Elevtid": func(a *Assignment, s *goquery.Selection, _ *time.Location) error {
+		text := strings.ReplaceAll(cellText(s), ",", ".")
+		if text == "" {
+			return nil
+		}
+		hours, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return fmt.Errorf("Invalid student time %q", cellText(s))
+		}
+		a.StudentHours = hours
+		return nil
+	},
+	"Status": func(a *Assignment, s *goquery.Selection, _ *time.Location) error {
+		a.Status = cellText(s)
+		return nil
+	},
+	"Fravær": func(a *Assignment, s *goquery.Selection, _ *time.Location) error {
+		a.Absence = cellText(s)
+		return nil
+	},
+	"Afventer": func(a *Assignment, s *goquery.Selection, _ *time.Location) error {
+		a.AwaitingFrom = cellText(s)
+		return nil
+	},
+	"Opgavenote": func(a *Assignment, s *goquery.Selection, _ *time.Location) error {
+		a.Note = cellText(s)
+		return nil
+	},
+	"Karakter": func(a *Assignment, s *goquery.Selection, _ *time.Location) error {
+		a.Grade = cellText(s)
+		return nil
+	},
+	"Elevnote": func(a *Assignment, s *goquery.Selection, _ *time.Location) error {
+		a.StudentNote = cellText(s)
+		return nil
+	},
+}
+
+// Returns the text of a table cell with surrounding and repeated whitespace removed
+func cellText(s *goquery.Selection) string {
+	return strings.Join(strings.Fields(s.Text()), " ")
+}
+
+// Parses a Lectio assignment page (OpgaverElev.aspx). Deadlines are read in the input location.
+// Columns are found by their header, so columns Lectio adds or leaves out do not matter. Assignments are returned in the order they appear in the page.
+// Problems with single rows are returned as warnings, while an error is returned if the assignment table itself cannot be read
+func ParseAssignments(r io.Reader, location *time.Location) ([]Assignment, []string, error) {
+	document, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	table := document.Find("table[id$='ExerciseGV']").First()
+	if table.Length() == 0 {
+		return nil, nil, ErrAssignmentsNotFound
+	}
+
+	var headers []string
+	table.Find("tr").First().Find("th").Each(func(i int, s *goquery.Selection) {
+		headers = append(headers, cellText(s))
+	})
+
+	var assignments []Assignment
+	var warnings []string
+	table.Find("tr").Each(func(row int, tr *goquery.Selection) {
+		cells := tr.Find("td")
+		if cells.Length() == 0 {
+			return
+		}
+
+		a := Assignment{}
+		cells.Each(func(col int, s *goquery.Selection) {
+			if col >= len(headers) {
+				return
+			}
+			parse, ok := assignmentColumns[headers[col]]
+			if !ok {
+				return
+			}
+			if err := parse(&a, s, location); err != nil {
+				warnings = append(warnings, fmt.Sprintf("row %d: %v", row, err))
+			}
+		})
+		if a.Id == "" {
+			warnings = append(warnings, fmt.Sprintf("row %d: no assignment ID found", row))
+			return
+		}
+		assignments = append(assignments, a)
+	})
+
+	return assignments, warnings, nil
+}
+
+// Converts an assignment to a Google Calendar event at its deadline, or an all-day event on the day of the deadline in the input location.
+// Missing assignments are red and handed in assignments green
+func (a *Assignment) ToGoogleEvent(allDay bool, location *time.Location) *GoogleEvent {
+	var description strings.Builder
+	fmt.Fprintf(&description, "Hold: %s\nStatus: %s\nFrist: %s\n", a.Team, a.Status, a.Deadline.In(location).Format("02/01-2006 15:04"))
+	if a.StudentHours > 0 {
+		fmt.Fprintf(&description, "Elevtid: %s timer\n", strconv.FormatFloat(a.StudentHours, 'f', -1, 64))
+	}
+	if a.Note != "" {
+		fmt.Fprintf(&description, "\n%s\n", a.Note)
+	}
+	if a.URL != "" {
+		fmt.Fprintf(&description, "\n%s\n", a.URL)
+	}
+
+	var colorID string
+	switch a.Status {
+	case "Mangler":
+		colorID = "4"
+	case "Afleveret":
+		colorID = "2"
+	}
+
+	event := &GoogleEvent{
+		Id:          AssignmentEventPrefix + a.Id,
+		Summary:     "Aflevering: " + a.Title,
+		Description: strings.TrimSpace(description.String()),
+		ColorId:     colorID,
+	}
+	deadline := a.Deadline.In(location)
+	if allDay {
+		day := time.Date(deadline.Year(), deadline.Month(), deadline.Day(), 0, 0, 0, 0, location)
+		event.Start = &calendar.EventDateTime{Date: day.Format(time.DateOnly)}
+		event.End = &calendar.EventDateTime{Date: day.AddDate(0, 0, 1).Format(time.DateOnly)}
+	} else {
+		event.Start = &calendar.EventDateTime{DateTime: deadline.Format(time.RFC3339), TimeZone: location.String()}
+		event.End = &calendar.EventDateTime{DateTime: deadline.Format(time.RFC3339), TimeZone: location.String()}
+	}
+	return event
+}
+
+// Gets the assignments of the logged in student
+func (l *Lectio) GetAssignments() ([]Assignment, error) {
+	studentID, err := l.StudentID()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := l.Client.Get(l.pageURL("OpgaverElev.aspx?elevid=" + url.QueryEscape(studentID)))
+	if err != nil {
+		return nil, requestError(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Could not get Lectio assignments: %w", statusError(response))
+	}
+
+	assignments, warnings, err := ParseAssignments(response.Body, l.Location)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse Lectio assignments: %w", err)
+	}
+	for _, warning := range warnings {
+		l.Logger.Printf("Lectio assignments: %s\n", warning)
+	}
+	for i, a := range assignments {
+		if u, err := response.Request.URL.Parse(a.URL); err == nil {
+			assignments[i].URL = u.String()
+		}
+	}
+	return assignments, nil
+}
+
+// The student IDs in Lectio links
+var studentIDPattern = regexp.MustCompile(`elevid=(\d+)`)
+
+// Returns the ID (elevid) of the logged in student, as found in the links of the front page
+func (l *Lectio) StudentID() (string, error) {
+	if l.studentID != "" {
+		return l.studentID, nil
+	}
+
+	response, err := l.Client.Get(l.pageURL("forside.aspx"))
+	if err != nil {
+		return "", requestError(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", statusError(response)
+	}
+
+	document, err := goquery.NewDocumentFromReader(response.Body)
+	if err != nil {
+		return "", err
+	}
+	document.Find("a[href*='elevid=']").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		href, _ := s.Attr("href")
+		if match := studentIDPattern.FindStringSubmatch(href); match != nil {
+			l.studentID = match[1]
+			return false
+		}
+		return true
+	})
+	if l.studentID == "" {
+		return "", errors.New("Could not find the student ID in the Lectio front page")
+	}
+	return l.studentID, nil
+}
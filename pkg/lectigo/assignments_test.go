@@ -0,0 +1,55 @@
+package lectigo_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+)
+
+func TestParseAssignments(t *testing.T) {
+	assignments, warnings, err := lectigo.ParseAssignments(bytes.NewReader(readTestdata(t, "assignments", "opgaver.html")), copenhagen(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Got warnings %q of the saved page", warnings)
+	}
+	checkGolden(t, assignments, "assignments", "opgaver.golden.json")
+}
+
+// An assignment table with a valid row between a row with a bad deadline and a row with no link
+const badAssignmentRows = `<table id="s_m_Content_Content_ExerciseGV">
+<tr><th>Hold</th><th>Opgavetitel</th><th>Frist</th><th>Status</th></tr>
+<tr><td>3a Dansk</td><td><a href="ElevAflevering.aspx?exerciseid=70000101">Analyse</a></td><td>i morgen</td><td>Venter</td></tr>
+<tr><td>3a Engelsk</td><td><a href="ElevAflevering.aspx?exerciseid=70000103">Essay</a></td><td>20/12-2024 12:00</td><td>Venter</td></tr>
+<tr><td>3a Fysik B</td><td>Rapport</td><td>10/1-2025 23:59</td><td>Venter</td></tr>
+</table>`
+
+func TestParseAssignmentsBadRows(t *testing.T) {
+	assignments, warnings, err := lectigo.ParseAssignments(strings.NewReader(badAssignmentRows), copenhagen(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The row with a bad deadline is kept with the fields that could be read, and the row with no ID is left out
+	var ids []string
+	for _, a := range assignments {
+		ids = append(ids, a.Id)
+	}
+	if strings.Join(ids, ",") != "70000101,70000103" {
+		t.Errorf("Got assignments %v, want 70000101 and 70000103", ids)
+	}
+	if len(warnings) != 2 || !strings.HasPrefix(warnings[0], "row 1:") || !strings.HasPrefix(warnings[1], "row 3:") {
+		t.Errorf("Got warnings %q, want one for row 1 and one for row 3", warnings)
+	}
+}
+
+func TestParseAssignmentsNotFound(t *testing.T) {
+	_, _, err := lectigo.ParseAssignments(strings.NewReader("<html><body><p>Log ind</p></body></html>"), copenhagen(t))
+	if !errors.Is(err, lectigo.ErrAssignmentsNotFound) {
+		t.Errorf("Got error %v, want %v", err, lectigo.ErrAssignmentsNotFound)
+	}
+}
@@ -0,0 +1,80 @@
+package lectigo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The parts of a module a change can concern
+const (
+	ChangedStatus = "status" // The module became "aflyst" or "ændret", or went back to normal
+	ChangedRoom   = "room"   // The module moved to another room
+	ChangedTime   = "time"   // The module moved to another time
+)
+
+// A change to a module found when updating Google Calendar
+type ModuleChange struct {
+	Module   Module   `json:"module"`   // The module as it is in Lectio now
+	Previous Module   `json:"previous"` // The module as it was in Google Calendar
+	Fields   []string `json:"fields"`   // What changed (ChangedStatus, ChangedRoom and/or ChangedTime)
+}
+
+// Returns the changes between the module as it was in Google Calendar and as it is in Lectio, or nil if none of the status, room and time changed
+func NewModuleChange(previous, current *Module) *ModuleChange {
+	var fields []string
+	if previous.ModuleStatus != current.ModuleStatus {
+		fields = append(fields, ChangedStatus)
+	}
+	if previous.Room != current.Room {
+		fields = append(fields, ChangedRoom)
+	}
+	if !previous.StartDate.Equal(current.StartDate) || !previous.EndDate.Equal(current.EndDate) {
+		fields = append(fields, ChangedTime)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return &ModuleChange{Module: *current, Previous: *previous, Fields: fields}
+}
+
+// Reports whether the given part of the module changed
+func (c *ModuleChange) Changed(field string) bool {
+	for _, f := range c.Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Describes the change in one line (eg. "3a Dansk 09/12 08:15: room 22 -> 31")
+func (c ModuleChange) String() string {
+	var parts []string
+	if c.Changed(ChangedStatus) {
+		parts = append(parts, c.Module.ModuleStatus)
+	}
+	if c.Changed(ChangedRoom) {
+		parts = append(parts, fmt.Sprintf("room %s -> %s", c.Previous.Room, c.Module.Room))
+	}
+	if c.Changed(ChangedTime) {
+		parts = append(parts, fmt.Sprintf("moved from %s to %s", formatPeriod(c.Previous.StartDate, c.Previous.EndDate), formatPeriod(c.Module.StartDate, c.Module.EndDate)))
+	}
+	return fmt.Sprintf("%s %s: %s", c.Module.Title, c.Module.StartDate.Format("02/01 15:04"), strings.Join(parts, ", "))
+}
+
+func formatPeriod(start, end time.Time) string {
+	return start.Format("02/01 15:04") + "-" + end.Format("15:04")
+}
+
+// Returns the changes to modules starting from the given time until the given amount of days later
+func ChangesWithin(changes []ModuleChange, from time.Time, days int) []ModuleChange {
+	until := from.AddDate(0, 0, days)
+	var within []ModuleChange
+	for _, c := range changes {
+		if c.Module.EndDate.After(from) && c.Module.StartDate.Before(until) {
+			within = append(within, c)
+		}
+	}
+	return within
+}
@@ -0,0 +1,162 @@
+package lectigo
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mattismoel/lectigo/util"
+	"google.golang.org/api/calendar/v3"
+)
+
+// An event lasting whole days, shown above the modules of the schedule (eg. excursions, exam days and holidays)
+type DayEvent struct {
+	Id        string    `json:"id"`        // The ID of the activity, or a hash of the title and first day for notices without one
+	Title     string    `json:"title"`     // The title of the event (eg. "Ekskursion til Berlin")
+	StartDate time.Time `json:"startDate"` // The first day of the event, at midnight
+	EndDate   time.Time `json:"endDate"`   // The last day of the event, at midnight
+	Team      string    `json:"team"`      // The team (hold) of the event, if any
+	Teacher   string    `json:"teacher"`   // The teachers of the event, if any
+	Room      string    `json:"room"`      // The room of the event, if any
+	Note      string    `json:"note"`      // The remaining info of the event
+	Status    string    `json:"status"`    // The status of the event ("uændret", "ændret" or "aflyst")
+}
+
+// Parses the day events of a Lectio schedule page (SkemaNy.aspx) of the input ISO week. Dates are read in the input location.
+// An event shown on several days is returned once, spanning the days
+func ParseDayEvents(r io.Reader, week util.Week, location *time.Location) ([]DayEvent, error) {
+	document, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+	_, dayEvents, _, err := parseScheduleDocument(document, week, location)
+	return dayEvents, err
+}
+
+// Parses the day events of the info row (td.s2infoHeader) of the schedule table. The first column is the sidebar, and the rest are the days from weekStart
+func parseDayEvents(row *goquery.Selection, weekStart time.Time) []DayEvent {
+	var events []DayEvent
+	row.Find("td").Each(func(col int, s *goquery.Selection) {
+		if col == 0 {
+			return
+		}
+		date := weekStart.AddDate(0, 0, col-1)
+
+		s.Find(".s2skemabrik").Each(func(i int, e *goquery.Selection) {
+			event := parseDayEvent(e, date)
+			if event.Title == "" {
+				return
+			}
+			if event.Id == "" {
+				// Notices without an ID continue the notice of the same title on the day before, if any
+				event.Id = fingerprint(event.Title + date.Format(time.DateOnly))
+				for _, previous := range events {
+					if previous.Title == event.Title && previous.EndDate.Equal(date.AddDate(0, 0, -1)) {
+						event.Id = previous.Id
+					}
+				}
+			}
+			events = addDayEvent(events, event)
+		})
+	})
+	return events
+}
+
+// Parses a single day event (.s2skemabrik) of the input date. The ID is empty if the event links to no activity
+func parseDayEvent(e *goquery.Selection, date time.Time) DayEvent {
+	event := DayEvent{
+		Title:     cellText(e.Find("div.s2skemabrikcontent")),
+		StartDate: date,
+		EndDate:   date,
+		Status:    "uændret",
+	}
+
+	if href, ok := e.Attr("href"); ok {
+		if u, err := url.Parse(href); err == nil {
+			event.Id = firstNonEmpty(u.Query().Get("absid"), u.Query().Get("aftaleid"))
+		}
+	}
+
+	addInfo, _ := e.Attr("data-additionalinfo")
+	header, note, _ := strings.Cut(addInfo, "\n\n")
+	note = strings.TrimSpace(note)
+	event.Note = strings.TrimSpace(strings.TrimPrefix(note, "Note:"))
+	for i, line := range strings.Split(header, "\n") {
+		line = strings.TrimSpace(line)
+		key, value, _ := strings.Cut(line, ": ")
+		switch {
+		case i == 0 && (line == "Ændret!" || line == "Aflyst!"):
+			event.Status = strings.ToLower(strings.TrimSuffix(line, "!"))
+		case key == "Hold":
+			event.Team = value
+		case key == "Lærer" || key == "Lærere":
+			event.Teacher = value
+		case key == "Lokale" || key == "Lokaler":
+			event.Room = value
+		case event.Title == "" && line != "":
+			event.Title = line
+		}
+	}
+	return event
+}
+
+// Adds the event to the events. An event with the same ID is extended to also span the days of the input event
+func addDayEvent(events []DayEvent, event DayEvent) []DayEvent {
+	for i, e := range events {
+		if e.Id != event.Id {
+			continue
+		}
+		if event.StartDate.Before(e.StartDate) {
+			events[i].StartDate = event.StartDate
+		}
+		if event.EndDate.After(e.EndDate) {
+			events[i].EndDate = event.EndDate
+		}
+		return events
+	}
+	return append(events, event)
+}
+
+// Returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Converts a day event to an all-day Google Calendar event. Cancelled events are red and changed events green, like modules
+func (e *DayEvent) ToGoogleEvent() *GoogleEvent {
+	var description strings.Builder
+	for _, field := range [][2]string{{"Hold", e.Team}, {"Lærer", e.Teacher}, {"Lokale", e.Room}} {
+		if field[1] != "" {
+			fmt.Fprintf(&description, "%s: %s\n", field[0], field[1])
+		}
+	}
+	if e.Note != "" {
+		fmt.Fprintf(&description, "\n%s\n", e.Note)
+	}
+
+	var colorID string
+	switch e.Status {
+	case "aflyst":
+		colorID = "4"
+	case "ændret":
+		colorID = "2"
+	}
+
+	return &GoogleEvent{
+		Id:          DayEventPrefix + e.Id,
+		Summary:     e.Title,
+		Description: strings.TrimSpace(description.String()),
+		Location:    e.Room,
+		ColorId:     colorID,
+		Start:       &calendar.EventDateTime{Date: e.StartDate.Format(time.DateOnly)},
+		End:         &calendar.EventDateTime{Date: e.EndDate.AddDate(0, 0, 1).Format(time.DateOnly)},
+	}
+}
@@ -0,0 +1,97 @@
+package lectigo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Returned when a page does not contain the Lectio list of schedules
+var ErrEntitiesNotFound = errors.New("Could not find a list of schedules in the Lectio page")
+
+// The type parameter of the find-schedule page (FindSkema.aspx) listing the schedules of each kind of target
+var findScheduleTypes = map[ScheduleTargetKind]string{
+	TargetTeacher: "laerer",
+	TargetRoom:    "lokale",
+	TargetClass:   "stamklasse",
+}
+
+// A teacher, room or class with a schedule in Lectio
+type ScheduleEntity struct {
+	Kind ScheduleTargetKind `json:"kind" yaml:"kind" xml:"kind"`
+	Id   string             `json:"id" yaml:"id" xml:"id"`       // The Lectio ID (eg. "9001")
+	Name string             `json:"name" yaml:"name" xml:"name"` // The name as Lectio shows it (eg. "Kasper Larsen (KL)" or "3a")
+}
+
+// Returns the target of the schedule of the entity
+func (e *ScheduleEntity) Target() ScheduleTarget {
+	return ScheduleTarget{Kind: e.Kind, Id: e.Id}
+}
+
+// Parses a Lectio find-schedule page (FindSkema.aspx) listing the schedules of a kind of target.
+// Each schedule is a link with the ID of the target in the query parameter of its kind (eg. laererid). Entities are sorted by name
+func ParseScheduleEntities(r io.Reader, kind ScheduleTargetKind) ([]ScheduleEntity, error) {
+	document, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	list := document.Find("[id$='listecontainer']").First()
+	if list.Length() == 0 {
+		return nil, ErrEntitiesNotFound
+	}
+
+	seen := make(map[string]bool)
+	var entities []ScheduleEntity
+	list.Find("a[href]").Each(func(i int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		u, err := url.Parse(href)
+		if err != nil {
+			return
+		}
+		id := u.Query().Get(string(kind))
+		if id == "" || seen[id] {
+			return
+		}
+		seen[id] = true
+		entities = append(entities, ScheduleEntity{Kind: kind, Id: id, Name: cellText(a)})
+	})
+
+	sort.SliceStable(entities, func(i, j int) bool {
+		return strings.ToLower(entities[i].Name) < strings.ToLower(entities[j].Name)
+	})
+	return entities, nil
+}
+
+// Gets the teachers, rooms or classes with a schedule at the school. The find-schedule pages are public, so no login is needed
+func GetScheduleEntities(client *http.Client, baseURL, schoolID string, kind ScheduleTargetKind) ([]ScheduleEntity, error) {
+	findType, ok := findScheduleTypes[kind]
+	if !ok {
+		return nil, fmt.Errorf("Cannot list the schedules of %q", kind)
+	}
+	if client == nil {
+		client = &http.Client{CheckRedirect: checkRedirect}
+	}
+
+	response, err := client.Get(fmt.Sprintf("%s/%s/FindSkema.aspx?type=%s", baseURL, schoolID, findType))
+	if err != nil {
+		return nil, requestError(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Could not get Lectio list of schedules: %w", statusError(response))
+	}
+
+	entities, err := ParseScheduleEntities(response.Body, kind)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse Lectio list of schedules: %v", err)
+	}
+	return entities, nil
+}
@@ -0,0 +1,38 @@
+package lectigo_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+)
+
+func TestParseScheduleEntities(t *testing.T) {
+	// The saved find-schedule pages (FindSkema.aspx) are named by their type parameter
+	tests := []struct {
+		kind     lectigo.ScheduleTargetKind
+		findType string
+	}{
+		{lectigo.TargetTeacher, "laerer"},
+		{lectigo.TargetRoom, "lokale"},
+		{lectigo.TargetClass, "stamklasse"},
+	}
+	for _, test := range tests {
+		t.Run(test.findType, func(t *testing.T) {
+			entities, err := lectigo.ParseScheduleEntities(bytes.NewReader(readTestdata(t, "entities", test.findType+".html")), test.kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, entities, "entities", test.findType+".golden.json")
+		})
+	}
+}
+
+func TestParseScheduleEntitiesNotFound(t *testing.T) {
+	_, err := lectigo.ParseScheduleEntities(strings.NewReader("<html><body><p>Log ind</p></body></html>"), lectigo.TargetTeacher)
+	if !errors.Is(err, lectigo.ErrEntitiesNotFound) {
+		t.Errorf("Got error %v, want %v", err, lectigo.ErrEntitiesNotFound)
+	}
+}
@@ -0,0 +1,73 @@
+package lectigo
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Decides which Lectio modules are synced, by their title and status
+type ModuleFilter struct {
+	Include         []*regexp.Regexp // If not empty, only modules with a title matching one of these are kept
+	Exclude         []*regexp.Regexp // Modules with a title matching one of these are removed
+	ExcludeStatuses []string         // Modules with one of these statuses (eg. "aflyst") are removed
+}
+
+// Creates a module filter from regular expressions matching module titles and a list of statuses to exclude
+func NewModuleFilter(include, exclude, excludeStatuses []string) (*ModuleFilter, error) {
+	filter := &ModuleFilter{}
+
+	for _, pattern := range include {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid include pattern %q: %v", pattern, err)
+		}
+		filter.Include = append(filter.Include, re)
+	}
+	for _, pattern := range exclude {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid exclude pattern %q: %v", pattern, err)
+		}
+		filter.Exclude = append(filter.Exclude, re)
+	}
+	for _, status := range excludeStatuses {
+		filter.ExcludeStatuses = append(filter.ExcludeStatuses, strings.ToLower(status))
+	}
+
+	return filter, nil
+}
+
+// Reports whether the module passes the filter
+func (f *ModuleFilter) Keep(m *Module) bool {
+	for _, status := range f.ExcludeStatuses {
+		if m.ModuleStatus == status {
+			return false
+		}
+	}
+	for _, re := range f.Exclude {
+		if re.MatchString(m.Title) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, re := range f.Include {
+		if re.MatchString(m.Title) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the modules passing the filter
+func (f *ModuleFilter) Apply(modules map[string]Module) map[string]Module {
+	filtered := make(map[string]Module)
+	for id, module := range modules {
+		if f.Keep(&module) {
+			filtered[id] = module
+		}
+	}
+	return filtered
+}
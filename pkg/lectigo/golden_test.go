@@ -0,0 +1,69 @@
+package lectigo_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden JSON of the saved Lectio pages with the parsed results")
+
+// The saved Lectio pages served by lectiotest.Server, with the golden JSON of each
+var testdataDir = filepath.Join("lectiotest", "testdata")
+
+// Reads a saved page or golden from the testdata of lectiotest (eg. "grades", "karakterer.html")
+func readTestdata(t *testing.T, elem ...string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(append([]string{testdataDir}, elem...)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Compares v as indented JSON to the golden file in the testdata of lectiotest, or rewrites the golden when run with -update.
+// Reports the first line that differs
+func checkGolden(t *testing.T, v any, elem ...string) {
+	t.Helper()
+	got, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	file := filepath.Join(append([]string{testdataDir}, elem...)...)
+	if *update {
+		if err := os.WriteFile(file, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("%v. Run the test with -update to create it", err)
+	}
+	if bytes.Equal(got, want) {
+		return
+	}
+
+	gotLines := strings.Split(string(got), "\n")
+	wantLines := strings.Split(string(want), "\n")
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var gotLine, wantLine string
+		if i < len(gotLines) {
+			gotLine = gotLines[i]
+		}
+		if i < len(wantLines) {
+			wantLine = wantLines[i]
+		}
+		if gotLine != wantLine {
+			t.Errorf("%s differs at line %d:\n got: %s\nwant: %s", file, i+1, gotLine, wantLine)
+			return
+		}
+	}
+}
@@ -2,11 +2,12 @@ package lectigo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -18,33 +19,66 @@ import (
 
 // Base struct for a Google Calendar client
 type GoogleCalendar struct {
-	Service *calendar.Service
-	ID      string
-	Logger  *log.Logger
+	Service  *calendar.Service
+	ID       string
+	Logger   *log.Logger
+	Clock    util.Clock     // The clock used to determine the current week
+	Location *time.Location // The timezone of the school
+	Template *EventTemplate // The template of event summaries and descriptions. Nil uses the defaults of Module.ToGoogleEvent
 }
 
 // Base Google Calendar event struct.
 type GoogleEvent calendar.Event
 
+// The prefixes of the IDs of the events lectigo creates. Each kind of event has its own prefix, so they are synced separately
+const (
+	ModuleEventPrefix     = "lec" // Events of modules in the schedule
+	AssignmentEventPrefix = "leo" // Events of assignment deadlines
+	DayEventPrefix        = "led" // All-day events of day events (eg. excursions)
+)
+
+// The prefixes of all events created by lectigo
+var eventPrefixes = []string{ModuleEventPrefix, AssignmentEventPrefix, DayEventPrefix}
+
+// Reports whether the event ID is of an event created by lectigo
+func isLectigoEvent(id string) bool {
+	for _, prefix := range eventPrefixes {
+		if strings.HasPrefix(id, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // Creates a new Google Calendar struct instance
-func NewGoogleCalendar(client *http.Client, calendarID string) (*GoogleCalendar, error) {
+func NewGoogleCalendar(client *http.Client, calendarID string, opts ...Option) (*GoogleCalendar, error) {
+	o := newOptions(opts)
 	ctx := context.Background()
 
-	service, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+	serviceOpts := append([]option.ClientOption{option.WithHTTPClient(client)}, o.calendarOptions...)
+	service, err := calendar.NewService(ctx, serviceOpts...)
 	if err != nil {
 		return nil, err
 	}
 
 	calendar := &GoogleCalendar{
-		Service: service,
-		ID:      calendarID,
-		Logger:  log.New(os.Stdout, "google-calendar ", log.LstdFlags),
+		Service:  service,
+		ID:       calendarID,
+		Logger:   o.loggerOr("google-calendar "),
+		Clock:    o.clock,
+		Location: o.location,
+		Template: o.template,
 	}
 	return calendar, nil
 }
 
 // Returns all modules from Google Calendar.
 func (c *GoogleCalendar) GetEvents(weekCount int) (map[string]*GoogleEvent, error) {
+	return c.getEvents(ModuleEventPrefix, weekCount)
+}
+
+// Returns the events with IDs of the prefix in the coming weeks, including deleted ones
+func (c *GoogleCalendar) getEvents(prefix string, weekCount int) (map[string]*GoogleEvent, error) {
 	googleCalModules := make(map[string]*GoogleEvent)
 	pageToken := ""
 	eventCount := 0
@@ -52,10 +86,7 @@ func (c *GoogleCalendar) GetEvents(weekCount int) (map[string]*GoogleEvent, erro
 	wg := sync.WaitGroup{}
 	mu := sync.RWMutex{}
 
-	startDate, err := util.GetMonday()
-	if err != nil {
-		return nil, err
-	}
+	startDate := util.GetMonday(c.Clock, c.Location)
 	endDate := startDate.AddDate(0, 0, 7*weekCount)
 	req := c.Service.Events.List(c.ID).ShowDeleted(true).TimeMin(startDate.Format(time.RFC3339)).TimeMax(endDate.Format(time.RFC3339))
 	for {
@@ -67,7 +98,7 @@ func (c *GoogleCalendar) GetEvents(weekCount int) (map[string]*GoogleEvent, erro
 			return nil, err
 		}
 		for _, item := range r.Items {
-			if strings.HasPrefix(item.Id, "lec") {
+			if strings.HasPrefix(item.Id, prefix) {
 				wg.Add(1)
 				go func(item *calendar.Event) {
 					defer wg.Done()
@@ -89,11 +120,62 @@ func (c *GoogleCalendar) GetEvents(weekCount int) (map[string]*GoogleEvent, erro
 	return googleCalModules, nil
 }
 
-// Updates the Google Calendar with the input Lectio modules and Google Calendar events. The modules input should not be filtered, as the functions handles that (input all modules from Lectio and all events from Google Calendar)
-func (c *GoogleCalendar) UpdateCalendar(lectioModules map[string]Module, googleEvents map[string]*GoogleEvent) error {
+// The outcome of updating Google Calendar with Lectio modules
+type SyncResult struct {
+	Inserted int           `json:"inserted"` // Events inserted into Google Calendar
+	Updated  int           `json:"updated"`  // Events updated in Google Calendar
+	Deleted  int           `json:"deleted"`  // Events deleted from Google Calendar
+	Failed   int           `json:"failed"`   // Events that could not be inserted, updated or deleted
+	Duration time.Duration `json:"duration"` // How long the update took
+
+	Changes []ModuleChange `json:"changes,omitempty"` // Changes to the status, room or time of modules already in the calendar
+}
+
+// Adds the counts and changes of another result to the result
+func (r *SyncResult) Add(other *SyncResult) {
+	if other == nil {
+		return
+	}
+	r.Inserted += other.Inserted
+	r.Updated += other.Updated
+	r.Deleted += other.Deleted
+	r.Failed += other.Failed
+	r.Duration += other.Duration
+	r.Changes = append(r.Changes, other.Changes...)
+}
+
+func (r *SyncResult) String() string {
+	return fmt.Sprintf(`
+RESULTS ==============================
+UPDATED %v events in Google Calendar
+INSERTED %v events into Google Calendar
+DELETED %v events from Google Calendar
+FAILED %v events
+
+Execution took %v
+======================================`,
+		r.Updated, r.Inserted, r.Deleted, r.Failed, r.Duration)
+}
+
+// Updates the Google Calendar with the input Lectio modules and Google Calendar events. The modules input should not be filtered, as the functions handles that (input all modules from Lectio and all events from Google Calendar).
+// Events that fail to update are skipped, and their errors are returned together once all other events have been handled. The result is returned in either case
+func (c *GoogleCalendar) UpdateCalendar(lectioModules map[string]Module, googleEvents map[string]*GoogleEvent) (*SyncResult, error) {
 	var inserted int // For keeping track of inserted events count after execution
 	var updated int  // For keeping track of updated events count after execution
 	var deleted int  // For keeping track of deleted events count after execution
+	var errs []error // Errors of the events that could not be updated
+	var changes []ModuleChange
+
+	mu := sync.Mutex{}
+	count := func(counter *int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		*counter++
+	}
 
 	startTime := time.Now()
 	// Loops through each module in the Lectio schedule and checks for differences between it and the Google Calendar
@@ -103,39 +185,53 @@ func (c *GoogleCalendar) UpdateCalendar(lectioModules map[string]Module, googleE
 
 	for lectioKey, lectioModule := range lectioModules {
 		wg.Add(1)
-		go func(lKey string, lModule Module) error {
+		go func(lKey string, lModule Module) {
 			defer wg.Done()
 			// If Lectio module is in Google Calendar
-			key := "lec" + lKey
+			key := ModuleEventPrefix + lKey
 			if _, ok := googleEvents[key]; ok {
 				googleEvent := *googleEvents[key]
-				googleModule, err := googleEvent.ToModule()
+				googleModule, err := googleEvent.ToModule(c.Location)
 				if err != nil {
-					return err
+					count(&updated, fmt.Errorf("Could not read event %v: %v", key, err))
+					return
 				}
-				needsUpdate := !lModule.Equals(googleModule)
+				lectioEvent, err := c.moduleEvent(&lModule)
+				if err != nil {
+					count(&updated, err)
+					return
+				}
+				// The descriptions are compared too, as the homework cannot be read back from the event
+				needsUpdate := !lModule.Equals(googleModule) || lectioEvent.Description != googleEvent.Description
 				isCancelled := googleEvent.Status == "cancelled"
 
 				if needsUpdate || isCancelled {
 					c.Logger.Printf("Attempting to update %v\n", googleEvent.Id)
-					lectioEvent := calendar.Event(*lModule.ToGoogleEvent())
-					_, err := c.Service.Events.Update(c.ID, googleEvent.Id, &lectioEvent).Do()
+					_, err = c.Service.Events.Update(c.ID, googleEvent.Id, lectioEvent).Do()
 					if err != nil {
-						return err
+						err = fmt.Errorf("Could not update event %v: %w", key, err)
+					}
+					count(&updated, err)
+
+					// Events deleted from the calendar are restored, which is not a change to the module
+					if change := NewModuleChange(googleModule, &lModule); err == nil && !isCancelled && change != nil {
+						mu.Lock()
+						changes = append(changes, *change)
+						mu.Unlock()
 					}
-					updated++
-				} else {
-					return nil
 				}
 			} else {
-				googleEvent := calendar.Event(*lModule.ToGoogleEvent())
-				_, err := c.Service.Events.Insert(c.ID, &googleEvent).Do()
+				googleEvent, err := c.moduleEvent(&lModule)
 				if err != nil {
-					return err
+					count(&inserted, err)
+					return
 				}
-				inserted++
+				_, err = c.Service.Events.Insert(c.ID, googleEvent).Do()
+				if err != nil {
+					err = fmt.Errorf("Could not insert event %v: %w", key, err)
+				}
+				count(&inserted, err)
 			}
-			return nil
 		}(lectioKey, lectioModule)
 	}
 
@@ -144,44 +240,194 @@ func (c *GoogleCalendar) UpdateCalendar(lectioModules map[string]Module, googleE
 	// Loops through all Google Events and checks if it should be deleted
 	for googleKey, googleEvent := range googleEvents {
 		wg.Add(1)
-		go func(googleKey string, googleEvent *GoogleEvent) error {
+		go func(googleKey string, googleEvent *GoogleEvent) {
 			defer wg.Done()
-			trimPrefix := strings.TrimPrefix(googleKey, "lec")
+			trimPrefix := strings.TrimPrefix(googleKey, ModuleEventPrefix)
 
 			if _, ok := lectioModules[trimPrefix]; !ok {
 				if googleEvent.Status != "cancelled" {
 					c.Logger.Printf("Attempting to delete %v\n", googleKey)
 					err := c.Service.Events.Delete(c.ID, googleKey).Do()
 					if err != nil {
-						return err
+						err = fmt.Errorf("Could not delete event %v: %w", googleKey, err)
 					}
-					deleted++
+					count(&deleted, err)
 				}
 			}
-			return nil
 		}(googleKey, googleEvent)
 	}
 	wg.Wait()
 
-	fmt.Printf(`
-RESULTS ==============================
-UPDATED %v events in Google Calendar
-INSERTED %v events into Google Calendar
-DELETED %v events from Google Calendar
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Module.StartDate.Before(changes[j].Module.StartDate)
+	})
+	result := &SyncResult{
+		Inserted: inserted,
+		Updated:  updated,
+		Deleted:  deleted,
+		Failed:   len(errs),
+		Duration: time.Since(startTime),
+		Changes:  changes,
+	}
+	return result, errors.Join(errs...)
+}
 
-Execution took %v
-======================================`,
-		updated, inserted, deleted, time.Since(startTime))
-	return nil
+// Updates the events of the prefix to match the input events, which are keyed by event ID. Used for events other than modules, which are compared field by field.
+// Events that are missing are inserted, events that differ or were deleted are updated, and events of the prefix not in the input are deleted
+func (c *GoogleCalendar) updateEvents(prefix string, events map[string]*calendar.Event, googleEvents map[string]*GoogleEvent) (*SyncResult, error) {
+	startTime := time.Now()
+	result := &SyncResult{}
+	var errs []error
+
+	mu := sync.Mutex{}
+	count := func(counter *int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, err)
+			result.Failed++
+			return
+		}
+		*counter++
+	}
+
+	var wg sync.WaitGroup
+	for id, event := range events {
+		wg.Add(1)
+		go func(id string, event *calendar.Event) {
+			defer wg.Done()
+			googleEvent, ok := googleEvents[id]
+			if !ok {
+				_, err := c.Service.Events.Insert(c.ID, event).Do()
+				if err != nil {
+					err = fmt.Errorf("Could not insert event %v: %w", id, err)
+				}
+				count(&result.Inserted, err)
+				return
+			}
+			if googleEvent.Status == "cancelled" || !eventsEqual(event, googleEvent) {
+				c.Logger.Printf("Attempting to update %v\n", id)
+				_, err := c.Service.Events.Update(c.ID, id, event).Do()
+				if err != nil {
+					err = fmt.Errorf("Could not update event %v: %w", id, err)
+				}
+				count(&result.Updated, err)
+			}
+		}(id, event)
+	}
+	wg.Wait()
+
+	for id, googleEvent := range googleEvents {
+		if _, ok := events[id]; ok || googleEvent.Status == "cancelled" || !strings.HasPrefix(id, prefix) {
+			continue
+		}
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			c.Logger.Printf("Attempting to delete %v\n", id)
+			err := c.Service.Events.Delete(c.ID, id).Do()
+			if err != nil {
+				err = fmt.Errorf("Could not delete event %v: %w", id, err)
+			}
+			count(&result.Deleted, err)
+		}(id)
+	}
+	wg.Wait()
+
+	result.Duration = time.Since(startTime)
+	return result, errors.Join(errs...)
+}
+
+// Reports whether the event in Google Calendar shows the same as the event lectigo would create
+func eventsEqual(event *calendar.Event, googleEvent *GoogleEvent) bool {
+	return event.Summary == googleEvent.Summary &&
+		event.Description == googleEvent.Description &&
+		event.Location == googleEvent.Location &&
+		event.ColorId == googleEvent.ColorId &&
+		eventTimesEqual(event.Start, googleEvent.Start) &&
+		eventTimesEqual(event.End, googleEvent.End)
+}
+
+// Reports whether two event times are the same day (all-day events) or the same instant (timed events)
+func eventTimesEqual(a, b *calendar.EventDateTime) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Date != "" || b.Date != "" {
+		return a.Date == b.Date
+	}
+	at, err := time.Parse(time.RFC3339, a.DateTime)
+	if err != nil {
+		return false
+	}
+	bt, err := time.Parse(time.RFC3339, b.DateTime)
+	if err != nil {
+		return false
+	}
+	return at.Equal(bt)
 }
 
-// Clears the Google Calendar of Lectigo events
+// Syncs the deadlines of the assignments in the coming weeks to Google Calendar, as events at the deadline or as all-day events on the day of the deadline.
+// Assignment events no longer in Lectio are deleted
+func (c *GoogleCalendar) SyncAssignments(assignments []Assignment, weekCount int, allDay bool) (*SyncResult, error) {
+	googleEvents, err := c.getEvents(AssignmentEventPrefix, weekCount)
+	if err != nil {
+		return nil, err
+	}
+
+	startDate := util.GetMonday(c.Clock, c.Location)
+	endDate := startDate.AddDate(0, 0, 7*weekCount)
+	events := make(map[string]*calendar.Event)
+	for _, a := range assignments {
+		if a.Deadline.Before(startDate) || !a.Deadline.Before(endDate) {
+			continue
+		}
+		event := calendar.Event(*a.ToGoogleEvent(allDay, c.Location))
+		events[event.Id] = &event
+	}
+
+	return c.updateEvents(AssignmentEventPrefix, events, googleEvents)
+}
+
+// Syncs the day events of the coming weeks to Google Calendar as all-day events. Day events no longer in Lectio are deleted
+func (c *GoogleCalendar) SyncDayEvents(dayEvents []DayEvent, weekCount int) (*SyncResult, error) {
+	googleEvents, err := c.getEvents(DayEventPrefix, weekCount)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(map[string]*calendar.Event)
+	for _, e := range dayEvents {
+		event := calendar.Event(*e.ToGoogleEvent())
+		events[event.Id] = &event
+	}
+
+	return c.updateEvents(DayEventPrefix, events, googleEvents)
+}
+
+// Converts a Lectio module to the event inserted into Google Calendar, using the event template if set
+func (c *GoogleCalendar) moduleEvent(m *Module) (*calendar.Event, error) {
+	if c.Template == nil {
+		event := calendar.Event(*m.ToGoogleEvent())
+		return &event, nil
+	}
+	googleEvent, err := c.Template.ToGoogleEvent(m)
+	if err != nil {
+		return nil, fmt.Errorf("Could not execute event template for module %v: %v", m.Id, err)
+	}
+	event := calendar.Event(*googleEvent)
+	return &event, nil
+}
+
+// Clears the Google Calendar of Lectigo events: modules, assignment deadlines and day events
 func (c *GoogleCalendar) Clear() error {
 	s := time.Now()
 	pageToken := ""
 	eventCount := 0
 
 	wg := sync.WaitGroup{}
+	mu := sync.Mutex{}
+	var errs []error
 
 	for {
 		req := c.Service.Events.List(c.ID)
@@ -193,16 +439,18 @@ func (c *GoogleCalendar) Clear() error {
 			return err
 		}
 		for _, item := range r.Items {
-			if strings.Contains(item.Id, "lec") {
+			if isLectigoEvent(item.Id) {
 				wg.Add(1)
-				go func(item *calendar.Event) error {
+				go func(item *calendar.Event) {
 					defer wg.Done()
 					err := c.Service.Events.Delete(c.ID, item.Id).Do()
+					mu.Lock()
+					defer mu.Unlock()
 					if err != nil {
-						return err
+						errs = append(errs, fmt.Errorf("Could not delete event %v: %w", item.Id, err))
+						return
 					}
 					eventCount++
-					return nil
 				}(item)
 			}
 		}
@@ -213,28 +461,24 @@ func (c *GoogleCalendar) Clear() error {
 		}
 	}
 	wg.Wait()
-	log.Printf("Found and deleted %v events in %v\n", eventCount, time.Since(s))
-	return nil
+	c.Logger.Printf("Found and deleted %v events in %v\n", eventCount, time.Since(s))
+	return errors.Join(errs...)
 }
 
-// Converts a Google Calendar event to a Lectio module
-func (e *GoogleEvent) ToModule() (*Module, error) {
-	location, err := time.LoadLocation("Europe/Copenhagen")
-	if err != nil {
-		return nil, err
-	}
-	start, err := time.ParseInLocation(time.RFC3339, e.Start.DateTime, location)
+// Converts a Google Calendar event to a Lectio module. The start and end dates are given in the input location
+func (e *GoogleEvent) ToModule(location *time.Location) (*Module, error) {
+	start, err := time.Parse(time.RFC3339, e.Start.DateTime)
 	if err != nil {
 		return nil, err
 	}
 
-	end, err := time.ParseInLocation(time.RFC3339, e.End.DateTime, location)
+	end, err := time.Parse(time.RFC3339, e.End.DateTime)
 	if err != nil {
 		return nil, err
 	}
 
 	var homework, teacher string
-	
+
 	re := regexp.MustCompile(`Lærer: \[(.*?)\]\nLektier:\n\[(.*?)\]`)
 	matches := re.FindStringSubmatch(e.Description)
 
@@ -244,10 +488,10 @@ func (e *GoogleEvent) ToModule() (*Module, error) {
 	}
 
 	module := &Module{
-		Id:           strings.TrimPrefix(e.Id, "lec"),
+		Id:           strings.TrimPrefix(e.Id, ModuleEventPrefix),
 		Title:        e.Summary,
-		StartDate:    start,
-		EndDate:      end,
+		StartDate:    start.In(location),
+		EndDate:      end.In(location),
 		Room:         e.Location,
 		Teacher:      teacher,
 		Homework:     homework,
@@ -0,0 +1,350 @@
+package lectigo_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/pkg/lectigo/googlecalendartest"
+	"github.com/mattismoel/lectigo/util"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// Modules on and around the DST weekends should keep their wall clock times when converted to events and back
+func TestToModuleDST(t *testing.T) {
+	location := copenhagen(t)
+	tests := []struct {
+		name       string
+		start, end time.Time
+	}{
+		{"friday before the last sunday of march", time.Date(2025, time.March, 28, 8, 15, 0, 0, location), time.Date(2025, time.March, 28, 9, 45, 0, 0, location)},
+		{"last sunday of march", time.Date(2025, time.March, 30, 10, 0, 0, 0, location), time.Date(2025, time.March, 30, 11, 30, 0, 0, location)},
+		{"monday after the last sunday of march", time.Date(2025, time.March, 31, 8, 15, 0, 0, location), time.Date(2025, time.March, 31, 9, 45, 0, 0, location)},
+		{"friday before the last sunday of october", time.Date(2025, time.October, 24, 8, 15, 0, 0, location), time.Date(2025, time.October, 24, 9, 45, 0, 0, location)},
+		{"last sunday of october", time.Date(2025, time.October, 26, 10, 0, 0, 0, location), time.Date(2025, time.October, 26, 11, 30, 0, 0, location)},
+		{"monday after the last sunday of october", time.Date(2025, time.October, 27, 8, 15, 0, 0, location), time.Date(2025, time.October, 27, 9, 45, 0, 0, location)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			module := &lectigo.Module{Id: "61000100", Title: "3a Da", StartDate: test.start, EndDate: test.end, ModuleStatus: "uændret"}
+			event := module.ToGoogleEvent()
+			if event.Start.TimeZone != location.String() {
+				t.Errorf("Event is in timezone %q, want %q", event.Start.TimeZone, location)
+			}
+
+			got, err := event.ToModule(location)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.StartDate.Equal(test.start) || !got.EndDate.Equal(test.end) {
+				t.Errorf("Got module from %v to %v, want %v to %v", got.StartDate, got.EndDate, test.start, test.end)
+			}
+			if got.StartDate.Hour() != test.start.Hour() || got.StartDate.Location() != location {
+				t.Errorf("Got module starting at %v, want the wall clock time %v", got.StartDate, test.start)
+			}
+		})
+	}
+}
+
+// Events read from Google Calendar in UTC should be converted to the wall clock time of the school
+func TestToModuleUTC(t *testing.T) {
+	location := copenhagen(t)
+	tests := []struct {
+		start, end string // RFC 3339 times in UTC
+		wantStart  string // The wall clock time in Copenhagen
+	}{
+		{"2025-03-29T09:15:00Z", "2025-03-29T10:45:00Z", "2025-03-29 10:15"},
+		{"2025-03-31T06:15:00Z", "2025-03-31T07:45:00Z", "2025-03-31 08:15"},
+		{"2025-10-25T06:15:00Z", "2025-10-25T07:45:00Z", "2025-10-25 08:15"},
+		{"2025-10-27T07:15:00Z", "2025-10-27T08:45:00Z", "2025-10-27 08:15"},
+	}
+	for _, test := range tests {
+		event := &lectigo.GoogleEvent{
+			Id:    "lec61000100",
+			Start: &calendar.EventDateTime{DateTime: test.start},
+			End:   &calendar.EventDateTime{DateTime: test.end},
+		}
+		module, err := event.ToModule(location)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := module.StartDate.Format("2006-01-02 15:04"); got != test.wantStart {
+			t.Errorf("ToModule(%s) starts at %s, want %s", test.start, got, test.wantStart)
+		}
+	}
+}
+
+// Returns a GoogleCalendar using the fake API, at noon on Wednesday 11 December 2024 in Europe/Copenhagen
+func newCalendar(t *testing.T, server *googlecalendartest.Server) *lectigo.GoogleCalendar {
+	t.Helper()
+	location := copenhagen(t)
+	c, err := server.NewGoogleCalendar("primary",
+		lectigo.WithClock(util.NewFakeClock(time.Date(2024, time.December, 11, 12, 0, 0, 0, location))),
+		lectigo.WithLocation(location),
+		lectigo.WithLogger(log.New(io.Discard, "", 0)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// Returns a module of 3a Da on the day of December 2024 at 08:15 in Europe/Copenhagen
+func testModule(t *testing.T, id string, day int) lectigo.Module {
+	location := copenhagen(t)
+	return lectigo.Module{
+		Id:           id,
+		Title:        "3a Da",
+		StartDate:    time.Date(2024, time.December, day, 8, 15, 0, 0, location),
+		EndDate:      time.Date(2024, time.December, day, 9, 45, 0, 0, location),
+		Room:         "22",
+		Teacher:      "Jens Jensen (JJ)",
+		ModuleStatus: "uændret",
+	}
+}
+
+// Returns the calendar event lectigo creates for the module
+func moduleEvent(m lectigo.Module) *calendar.Event {
+	event := calendar.Event(*m.ToGoogleEvent())
+	return &event
+}
+
+// Returns the IDs of the events of the fake calendar that are not deleted
+func liveEvents(server *googlecalendartest.Server) []string {
+	var ids []string
+	for _, event := range server.Events("primary") {
+		if event.Status != "cancelled" {
+			ids = append(ids, event.Id)
+		}
+	}
+	return ids
+}
+
+func TestGetEventsPaging(t *testing.T) {
+	server := googlecalendartest.NewServer()
+	defer server.Close()
+	server.PageSize = 3
+
+	var want []string
+	for day := 9; day <= 20; day++ {
+		m := testModule(t, fmt.Sprintf("610001%02d", day), day)
+		server.AddEvent("primary", moduleEvent(m))
+		want = append(want, lectigo.ModuleEventPrefix+m.Id)
+	}
+	// Deleted events are returned, so they can be restored
+	deleted := testModule(t, "61000199", 10)
+	event := moduleEvent(deleted)
+	event.Status = "cancelled"
+	server.AddEvent("primary", event)
+	want = append(want, lectigo.ModuleEventPrefix+deleted.Id)
+	// Events before and after the synced weeks, and events not made by lectigo, are left out
+	server.AddEvent("primary", moduleEvent(testModule(t, "61000001", 6)))
+	server.AddEvent("primary", moduleEvent(testModule(t, "61000002", 23)))
+	personal := moduleEvent(testModule(t, "61000003", 10))
+	personal.Id = "tandlaege"
+	server.AddEvent("primary", personal)
+
+	c := newCalendar(t, server)
+	events, err := c.GetEvents(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for id, event := range events {
+		if id != event.Id {
+			t.Errorf("Event %s is keyed by %s", event.Id, id)
+		}
+		got = append(got, id)
+	}
+	sort.Strings(got)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetEvents(2) = %v, want %v", got, want)
+	}
+	// 14 events in the synced weeks, 3 per page
+	if requests := server.Requests(); requests != 5 {
+		t.Errorf("GetEvents made %d requests, want 5 pages", requests)
+	}
+}
+
+func TestUpdateCalendar(t *testing.T) {
+	server := googlecalendartest.NewServer()
+	defer server.Close()
+
+	unchanged := testModule(t, "61000101", 9)
+	moved := testModule(t, "61000102", 10)
+	removed := testModule(t, "61000103", 11)
+	restored := testModule(t, "61000104", 12)
+	added := testModule(t, "61000105", 13)
+
+	server.AddEvent("primary", moduleEvent(unchanged))
+	server.AddEvent("primary", moduleEvent(moved))
+	server.AddEvent("primary", moduleEvent(removed))
+	event := moduleEvent(restored)
+	event.Status = "cancelled"
+	server.AddEvent("primary", event)
+
+	moved.Room = "14"
+	modules := map[string]lectigo.Module{
+		unchanged.Id: unchanged,
+		moved.Id:     moved,
+		restored.Id:  restored,
+		added.Id:     added,
+	}
+
+	c := newCalendar(t, server)
+	events, err := c.GetEvents(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := c.UpdateCalendar(modules, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Inserted != 1 || result.Updated != 2 || result.Deleted != 1 || result.Failed != 0 {
+		t.Errorf("Inserted %d, updated %d, deleted %d and failed %d events, want 1 inserted, 2 updated and 1 deleted", result.Inserted, result.Updated, result.Deleted, result.Failed)
+	}
+	if len(result.Changes) != 1 || result.Changes[0].Module.Id != moved.Id {
+		t.Errorf("Got changes %+v, want the room change of %s", result.Changes, moved.Id)
+	}
+	if got := server.Event("primary", lectigo.ModuleEventPrefix+moved.Id).Location; got != "14" {
+		t.Errorf("Moved module is in room %q, want 14", got)
+	}
+	if got := server.Event("primary", lectigo.ModuleEventPrefix+restored.Id).Status; got != "confirmed" {
+		t.Errorf("Restored module has status %q, want confirmed", got)
+	}
+	if got := server.Event("primary", lectigo.ModuleEventPrefix+removed.Id).Status; got != "cancelled" {
+		t.Errorf("Removed module has status %q, want cancelled", got)
+	}
+	if server.Event("primary", lectigo.ModuleEventPrefix+added.Id) == nil {
+		t.Errorf("Added module was not inserted")
+	}
+
+	// The calendar now matches the modules
+	events, err = c.GetEvents(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = c.UpdateCalendar(modules, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inserted != 0 || result.Updated != 0 || result.Deleted != 0 {
+		t.Errorf("Second update inserted %d, updated %d and deleted %d events, want none", result.Inserted, result.Updated, result.Deleted)
+	}
+}
+
+func TestClear(t *testing.T) {
+	server := googlecalendartest.NewServer()
+	defer server.Close()
+	server.PageSize = 2
+
+	for i, prefix := range []string{lectigo.ModuleEventPrefix, lectigo.AssignmentEventPrefix, lectigo.DayEventPrefix} {
+		for day := 9; day <= 11; day++ {
+			event := moduleEvent(testModule(t, fmt.Sprintf("6100%d%03d", i, day), day))
+			event.Id = prefix + strings.TrimPrefix(event.Id, lectigo.ModuleEventPrefix)
+			server.AddEvent("primary", event)
+		}
+	}
+	personal := moduleEvent(testModule(t, "1", 10))
+	personal.Id = "tandlaege"
+	server.AddEvent("primary", personal)
+
+	if err := newCalendar(t, server).Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if got := liveEvents(server); !reflect.DeepEqual(got, []string{"tandlaege"}) {
+		t.Errorf("Events left after Clear: %v, want only tandlaege", got)
+	}
+}
+
+func TestCalendarErrors(t *testing.T) {
+	t.Run("list", func(t *testing.T) {
+		server := googlecalendartest.NewServer()
+		defer server.Close()
+		server.Fail(googlecalendartest.Method(http.MethodGet), http.StatusInternalServerError, 1)
+
+		_, err := newCalendar(t, server).GetEvents(2)
+		var apiErr *googleapi.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != http.StatusInternalServerError {
+			t.Errorf("Got error %v, want a 500 API error", err)
+		}
+	})
+
+	t.Run("unknown calendar", func(t *testing.T) {
+		server := googlecalendartest.NewServer()
+		defer server.Close()
+		c, err := server.NewGoogleCalendar("ukendt", lectigo.WithLogger(log.New(io.Discard, "", 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = c.GetEvents(2)
+		var apiErr *googleapi.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != http.StatusNotFound {
+			t.Errorf("Got error %v, want a 404 API error", err)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		server := googlecalendartest.NewServer()
+		defer server.Close()
+
+		moved := testModule(t, "61000101", 9)
+		removed := testModule(t, "61000102", 10)
+		added := testModule(t, "61000103", 11)
+		server.AddEvent("primary", moduleEvent(moved))
+		server.AddEvent("primary", moduleEvent(removed))
+		moved.Room = "14"
+		server.Fail(googlecalendartest.Method(http.MethodPut), http.StatusForbidden, 1)
+		server.Fail(googlecalendartest.Method(http.MethodDelete), http.StatusInternalServerError, 1)
+
+		c := newCalendar(t, server)
+		events, err := c.GetEvents(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := c.UpdateCalendar(map[string]lectigo.Module{moved.Id: moved, added.Id: added}, events)
+		var apiErr *googleapi.Error
+		if !errors.As(err, &apiErr) {
+			t.Errorf("Got error %v, want API errors", err)
+		}
+		if result == nil {
+			t.Fatal("No result returned with the error")
+		}
+		// The insert succeeds although the update and delete fail
+		if result.Failed != 2 || result.Inserted != 1 || result.Updated != 0 || result.Deleted != 0 {
+			t.Errorf("Inserted %d, updated %d, deleted %d and failed %d events, want 1 inserted and 2 failed", result.Inserted, result.Updated, result.Deleted, result.Failed)
+		}
+		if len(result.Changes) != 0 {
+			t.Errorf("Got changes %+v of a failed update", result.Changes)
+		}
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		server := googlecalendartest.NewServer()
+		defer server.Close()
+		for day := 9; day <= 11; day++ {
+			server.AddEvent("primary", moduleEvent(testModule(t, fmt.Sprintf("61000%03d", day), day)))
+		}
+		server.Fail(googlecalendartest.Method(http.MethodDelete), http.StatusInternalServerError, 1)
+
+		err := newCalendar(t, server).Clear()
+		var apiErr *googleapi.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != http.StatusInternalServerError {
+			t.Errorf("Got error %v, want a 500 API error", err)
+		}
+		if got := liveEvents(server); len(got) != 1 {
+			t.Errorf("Events left after Clear: %v, want only the one that failed", got)
+		}
+	})
+}
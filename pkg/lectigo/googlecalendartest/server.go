@@ -0,0 +1,384 @@
+// Package googlecalendartest provides an in-memory fake of the Google Calendar v3 events API for testing code that updates Google calendars.
+package googlecalendartest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+// The page size used when a list request does not set maxResults
+const DefaultPageSize = 250
+
+// A fake Google Calendar API serving the events endpoints (list, insert, update and delete) of a set of in-memory calendars.
+// Like the real API, deleted events are kept with the status "cancelled", so inserting an event with the ID of a deleted event fails with 409 Conflict
+type Server struct {
+	*httptest.Server
+	PageSize int // The page size used when a list request does not set maxResults
+
+	mu        sync.Mutex
+	calendars map[string]map[string]*calendar.Event // Events by ID by calendar ID
+	failures  []failure
+	requests  int
+}
+
+type failure struct {
+	match  func(r *http.Request) bool
+	status int
+	times  int // Number of times left to fail. Negative fails forever
+}
+
+// Creates and starts a fake Google Calendar API with the input (initially empty) calendars. If no calendar IDs are given, the calendar "primary" is created.
+// The caller should call Close when done
+func NewServer(calendarIDs ...string) *Server {
+	if len(calendarIDs) == 0 {
+		calendarIDs = []string{"primary"}
+	}
+
+	s := &Server{
+		PageSize:  DefaultPageSize,
+		calendars: make(map[string]map[string]*calendar.Event),
+	}
+	for _, id := range calendarIDs {
+		s.calendars[id] = make(map[string]*calendar.Event)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Returns the endpoint to pass to option.WithEndpoint
+func (s *Server) Endpoint() string {
+	return s.URL + "/calendar/v3/"
+}
+
+// Returns the options connecting a lectigo.GoogleCalendar to the server
+func (s *Server) Options() []lectigo.Option {
+	return []lectigo.Option{lectigo.WithCalendarOptions(option.WithEndpoint(s.Endpoint()))}
+}
+
+// Creates a lectigo.GoogleCalendar using the server as its API. Further options are applied after the ones connecting it to the server
+func (s *Server) NewGoogleCalendar(calendarID string, opts ...lectigo.Option) (*lectigo.GoogleCalendar, error) {
+	return lectigo.NewGoogleCalendar(s.Client(), calendarID, append(s.Options(), opts...)...)
+}
+
+// Adds an event directly to a calendar, bypassing the API. Useful for setting up a calendar before a test
+func (s *Server) AddEvent(calendarID string, event *calendar.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := *event
+	if e.Status == "" {
+		e.Status = "confirmed"
+	}
+	s.calendar(calendarID)[e.Id] = &e
+}
+
+// Returns a copy of all events of a calendar ordered by ID, including cancelled ones
+func (s *Server) Events(calendarID string) []*calendar.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []*calendar.Event
+	for _, event := range s.calendars[calendarID] {
+		e := *event
+		events = append(events, &e)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Id < events[j].Id
+	})
+	return events
+}
+
+// Returns a copy of the event with the input ID, or nil if it does not exist
+func (s *Server) Event(calendarID, eventID string) *calendar.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.calendars[calendarID][eventID]
+	if !ok {
+		return nil
+	}
+	e := *event
+	return &e
+}
+
+// Makes the next times requests matching the input function fail with the input HTTP status. If times is negative, the requests fail forever
+func (s *Server) Fail(match func(r *http.Request) bool, status int, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{match: match, status: status, times: times})
+}
+
+// Returns a function matching requests of the input HTTP method, for use with Fail
+func Method(method string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		return r.Method == method
+	}
+}
+
+// Returns the number of requests the server has received
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// Returns the events of a calendar, creating it if it does not exist. Must be called with the lock held
+func (s *Server) calendar(calendarID string) map[string]*calendar.Event {
+	events, ok := s.calendars[calendarID]
+	if !ok {
+		events = make(map[string]*calendar.Event)
+		s.calendars[calendarID] = events
+	}
+	return events
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests++
+	for i := range s.failures {
+		f := &s.failures[i]
+		if f.times != 0 && f.match(r) {
+			if f.times > 0 {
+				f.times--
+			}
+			s.mu.Unlock()
+			writeError(w, f.status, "backendError", "Injected failure")
+			return
+		}
+	}
+	s.mu.Unlock()
+
+	// Paths are of the form /calendar/v3/calendars/{calendarId}/events[/{eventId}]
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/calendar/v3/"), "/")
+	if len(parts) < 3 || parts[0] != "calendars" || parts[2] != "events" {
+		writeError(w, http.StatusNotFound, "notFound", "Not Found")
+		return
+	}
+
+	calendarID := parts[1]
+	s.mu.Lock()
+	_, ok := s.calendars[calendarID]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "notFound", "Not Found")
+		return
+	}
+
+	switch {
+	case len(parts) == 3 && r.Method == http.MethodGet:
+		s.list(w, r, calendarID)
+	case len(parts) == 3 && r.Method == http.MethodPost:
+		s.insert(w, r, calendarID)
+	case len(parts) == 4 && r.Method == http.MethodGet:
+		s.get(w, calendarID, parts[3])
+	case len(parts) == 4 && r.Method == http.MethodPut:
+		s.update(w, r, calendarID, parts[3])
+	case len(parts) == 4 && r.Method == http.MethodDelete:
+		s.delete(w, calendarID, parts[3])
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "methodNotAllowed", "Method not allowed")
+	}
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request, calendarID string) {
+	query := r.URL.Query()
+	showDeleted := query.Get("showDeleted") == "true"
+
+	var timeMin, timeMax time.Time
+	var err error
+	if v := query.Get("timeMin"); v != "" {
+		if timeMin, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid", "Bad Request")
+			return
+		}
+	}
+	if v := query.Get("timeMax"); v != "" {
+		if timeMax, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid", "Bad Request")
+			return
+		}
+	}
+
+	pageSize := s.PageSize
+	if v := query.Get("maxResults"); v != "" {
+		if pageSize, err = strconv.Atoi(v); err != nil || pageSize <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid", "Bad Request")
+			return
+		}
+	}
+
+	// Page tokens are the ID of the last event of the previous page. Events are ordered by ID, so deleting events while paging does not skip any, as with the real API
+	after := query.Get("pageToken")
+
+	var items []*calendar.Event
+	for _, event := range s.Events(calendarID) {
+		if after != "" && event.Id <= after {
+			continue
+		}
+		if event.Status == "cancelled" && !showDeleted {
+			continue
+		}
+		start, end, err := eventTimes(event)
+		if err != nil {
+			continue
+		}
+		// Like the real API, timeMin filters by end time and timeMax by start time
+		if !timeMin.IsZero() && !end.After(timeMin) {
+			continue
+		}
+		if !timeMax.IsZero() && !start.Before(timeMax) {
+			continue
+		}
+		items = append(items, event)
+	}
+
+	response := &calendar.Events{Kind: "calendar#events", Items: []*calendar.Event{}}
+	if len(items) > pageSize {
+		items = items[:pageSize]
+		response.NextPageToken = items[len(items)-1].Id
+	}
+	response.Items = append(response.Items, items...)
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) get(w http.ResponseWriter, calendarID, eventID string) {
+	event := s.Event(calendarID, eventID)
+	if event == nil {
+		writeError(w, http.StatusNotFound, "notFound", "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, event)
+}
+
+func (s *Server) insert(w http.ResponseWriter, r *http.Request, calendarID string) {
+	event := &calendar.Event{}
+	if err := json.NewDecoder(r.Body).Decode(event); err != nil {
+		writeError(w, http.StatusBadRequest, "parseError", "Parse Error")
+		return
+	}
+	if _, _, err := eventTimes(event); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := s.calendar(calendarID)
+	if event.Id == "" {
+		event.Id = fmt.Sprintf("fake%d", len(events))
+	}
+	if _, exists := events[event.Id]; exists {
+		writeError(w, http.StatusConflict, "duplicate", "The requested identifier already exists.")
+		return
+	}
+	if event.Status == "" {
+		event.Status = "confirmed"
+	}
+	events[event.Id] = event
+	writeJSON(w, http.StatusOK, event)
+}
+
+func (s *Server) update(w http.ResponseWriter, r *http.Request, calendarID, eventID string) {
+	event := &calendar.Event{}
+	if err := json.NewDecoder(r.Body).Decode(event); err != nil {
+		writeError(w, http.StatusBadRequest, "parseError", "Parse Error")
+		return
+	}
+	if _, _, err := eventTimes(event); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := s.calendar(calendarID)
+	if _, exists := events[eventID]; !exists {
+		writeError(w, http.StatusNotFound, "notFound", "Not Found")
+		return
+	}
+	event.Id = eventID
+	if event.Status == "" {
+		event.Status = "confirmed"
+	}
+	events[eventID] = event
+	writeJSON(w, http.StatusOK, event)
+}
+
+func (s *Server) delete(w http.ResponseWriter, calendarID, eventID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, exists := s.calendar(calendarID)[eventID]
+	if !exists {
+		writeError(w, http.StatusNotFound, "notFound", "Not Found")
+		return
+	}
+	if event.Status == "cancelled" {
+		writeError(w, http.StatusGone, "deleted", "Resource has been deleted")
+		return
+	}
+	event.Status = "cancelled"
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Returns the start and end of an event. All-day events start and end at midnight UTC
+func eventTimes(event *calendar.Event) (time.Time, time.Time, error) {
+	if event.Start == nil || event.End == nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("Missing start or end time.")
+	}
+	start, err := parseEventDateTime(event.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end, err := parseEventDateTime(event.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return start, end, nil
+}
+
+func parseEventDateTime(t *calendar.EventDateTime) (time.Time, error) {
+	if t.Date != "" {
+		return time.Parse(time.DateOnly, t.Date)
+	}
+	return time.Parse(time.RFC3339, t.DateTime)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Writes an error in the format of the Google APIs, so the client returns it as a *googleapi.Error
+func writeError(w http.ResponseWriter, status int, reason, message string) {
+	type errorItem struct {
+		Domain  string `json:"domain"`
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+	}
+	type errorBody struct {
+		Code    int         `json:"code"`
+		Message string      `json:"message"`
+		Errors  []errorItem `json:"errors"`
+	}
+	writeJSON(w, status, map[string]errorBody{
+		"error": {
+			Code:    status,
+			Message: message,
+			Errors:  []errorItem{{Domain: "global", Reason: reason, Message: message}},
+		},
+	})
+}
@@ -0,0 +1,190 @@
+package lectigo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Returned when a page does not contain the Lectio grade table
+var ErrGradesNotFound = errors.New("Could not find a grade table in the Lectio page")
+
+// The kind of a grade, found from the header of its column
+type GradeKind string
+
+const (
+	GradeTerm   GradeKind = "term"   // A term grade (standpunktskarakter)
+	GradeAnnual GradeKind = "annual" // An annual grade (årskarakter)
+	GradeExam   GradeKind = "exam"   // An exam or internal test grade (eksamens- eller prøvekarakter)
+	GradeOther  GradeKind = "other"  // Any other grade
+)
+
+// The grades of the Danish 7-point scale (7-trinsskalaen)
+var gradeScale = map[string]int{"-3": -3, "00": 0, "02": 2, "4": 4, "7": 7, "10": 10, "12": 12}
+
+// A grade given to a student in a team
+type Grade struct {
+	Team    string    `json:"team" yaml:"team" xml:"team"`          // The team (eg. "3a Dansk")
+	Subject string    `json:"subject" yaml:"subject" xml:"subject"` // The subject and level (eg. "Dansk A")
+	Kind    GradeKind `json:"kind" yaml:"kind" xml:"kind"`
+	Column  string    `json:"column" yaml:"column" xml:"column"` // The column of the grade in Lectio (eg. "1. standpunkt")
+	Grade   string    `json:"grade" yaml:"grade" xml:"grade"`    // The grade as shown by Lectio (eg. "02", "12" or "Bestået")
+	Weight  float64   `json:"weight" yaml:"weight" xml:"weight"` // The weight of the grade in the average. 1 if Lectio shows none
+}
+
+// Returns the grade as a number on the 7-point scale. Grades not on the scale, such as "Bestået", return false
+func (g *Grade) Value() (int, bool) {
+	v, ok := gradeScale[g.Grade]
+	return v, ok
+}
+
+// Returns the average of the grades of the input kinds, weighted by their weights. Grades not on the 7-point scale are left out.
+// Returns false if no grades were averaged
+func WeightedAverage(grades []Grade, kinds ...GradeKind) (float64, bool) {
+	var sum, weights float64
+	for _, g := range grades {
+		v, ok := g.Value()
+		if !ok || !containsKind(kinds, g.Kind) {
+			continue
+		}
+		sum += float64(v) * g.Weight
+		weights += g.Weight
+	}
+	if weights == 0 {
+		return 0, false
+	}
+	return sum / weights, true
+}
+
+func containsKind(kinds []GradeKind, kind GradeKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the kind of grades in a column of the grade table
+func gradeKind(header string) GradeKind {
+	header = strings.ToLower(header)
+	switch {
+	case strings.Contains(header, "standpunkt"):
+		return GradeTerm
+	case strings.Contains(header, "årskarakter"):
+		return GradeAnnual
+	case strings.Contains(header, "eksamen") || strings.Contains(header, "prøve"):
+		return GradeExam
+	}
+	return GradeOther
+}
+
+// Parses a Lectio grade overview page (grade_report.aspx). Each column after the team and subject holds a kind of grade.
+// The weight of a grade is read from its tooltip (eg. "Vægt: 1,50"), and is 1 if the tooltip has none. Grades are returned by team, in the order of the columns.
+// Grades with a weight that cannot be read keep the weight 1 and are returned with a warning, while an error is returned if the grade table itself cannot be read
+func ParseGrades(r io.Reader) ([]Grade, []string, error) {
+	document, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	table := document.Find("table[id$='KarakterGV']").First()
+	if table.Length() == 0 {
+		return nil, nil, ErrGradesNotFound
+	}
+
+	var headers []string
+	table.Find("tr").First().Find("th").Each(func(i int, s *goquery.Selection) {
+		headers = append(headers, cellText(s))
+	})
+
+	var grades []Grade
+	var warnings []string
+	table.Find("tr").Each(func(row int, tr *goquery.Selection) {
+		cells := tr.Find("td")
+		if cells.Length() == 0 {
+			return
+		}
+
+		var team, subject string
+		cells.Each(func(col int, s *goquery.Selection) {
+			if col >= len(headers) {
+				return
+			}
+			switch headers[col] {
+			case "Hold":
+				team = cellText(s)
+				return
+			case "Fag":
+				subject = cellText(s)
+				return
+			}
+
+			grade := Grade{Team: team, Subject: subject, Kind: gradeKind(headers[col]), Column: headers[col], Grade: cellText(s), Weight: 1}
+			if grade.Grade == "" {
+				return
+			}
+			title, _ := s.Attr("title")
+			if title == "" {
+				title, _ = s.Find("[title]").First().Attr("title")
+			}
+			weight, ok, err := parseGradeWeight(title)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("row %d (%s): %v", row, team, err))
+			} else if ok {
+				grade.Weight = weight
+			}
+			grades = append(grades, grade)
+		})
+	})
+
+	return grades, warnings, nil
+}
+
+// Parses the weight of a grade tooltip (eg. "Karakter: 7\nVægt: 1,50"). Reports whether the tooltip has a weight
+func parseGradeWeight(title string) (float64, bool, error) {
+	for _, line := range strings.Split(title, "\n") {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), ":")
+		if key != "Vægt" {
+			continue
+		}
+		weight, err := parseDanishFloat(value)
+		if err != nil {
+			return 0, true, fmt.Errorf("Invalid weight %q", strings.TrimSpace(value))
+		}
+		return weight, true, nil
+	}
+	return 0, false, nil
+}
+
+// Gets the grades of the logged in student
+func (l *Lectio) GetGrades() ([]Grade, error) {
+	studentID, err := l.StudentID()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := l.Client.Get(l.pageURL("grades/grade_report.aspx?elevid=" + url.QueryEscape(studentID)))
+	if err != nil {
+		return nil, requestError(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Could not get Lectio grades: %w", statusError(response))
+	}
+
+	grades, warnings, err := ParseGrades(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse Lectio grades: %w", err)
+	}
+	for _, warning := range warnings {
+		l.Logger.Printf("Lectio grades: %s\n", warning)
+	}
+	return grades, nil
+}
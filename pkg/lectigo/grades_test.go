@@ -0,0 +1,58 @@
+package lectigo_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+)
+
+func TestParseGrades(t *testing.T) {
+	grades, warnings, err := lectigo.ParseGrades(bytes.NewReader(readTestdata(t, "grades", "karakterer.html")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Got warnings %q of the saved page", warnings)
+	}
+	checkGolden(t, grades, "grades", "karakterer.golden.json")
+}
+
+func TestParseGradesNotFound(t *testing.T) {
+	_, _, err := lectigo.ParseGrades(strings.NewReader("<html><body><p>Log ind</p></body></html>"))
+	if !errors.Is(err, lectigo.ErrGradesNotFound) {
+		t.Errorf("Got error %v, want %v", err, lectigo.ErrGradesNotFound)
+	}
+}
+
+// Grades with no weight, a weight of 0, a bad weight and a weight of 2
+const gradeWeights = `<table id="s_m_Content_Content_karakterView_KarakterGV">
+<tr><th>Hold</th><th>Fag</th><th>1. standpunkt</th><th>2. standpunkt</th><th>Årskarakter</th><th>Eksamens-/prøvekarakter</th></tr>
+<tr><td>3a Dansk</td><td>Dansk A</td><td><span title="Karakter: 02">02</span></td><td><span title="Karakter: -3&#10;Vægt: 0,00">-3</span></td><td><span title="Karakter: 4&#10;Vægt: meget">4</span></td><td><span title="Karakter: 12&#10;Vægt: 2,00">12</span></td></tr>
+</table>`
+
+func TestParseGradesWeights(t *testing.T) {
+	grades, warnings, err := lectigo.ParseGrades(strings.NewReader(gradeWeights))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var weights []float64
+	for _, g := range grades {
+		weights = append(weights, g.Weight)
+	}
+	if !reflect.DeepEqual(weights, []float64{1, 0, 1, 2}) {
+		t.Errorf("Got weights %v, want 1 without a weight, 0, 1 for the bad weight and 2", weights)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `"meget"`) {
+		t.Errorf("Got warnings %q, want one for the bad weight", warnings)
+	}
+
+	// The grade of weight 0 is left out of the average
+	if average, ok := lectigo.WeightedAverage(grades, lectigo.GradeTerm); !ok || average != 2 {
+		t.Errorf("Got term average %v, %v, want 2", average, ok)
+	}
+}
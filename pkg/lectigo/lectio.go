@@ -6,15 +6,13 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"net/http/cookiejar"
-	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
-	"github.com/gocolly/colly"
 	"github.com/mattismoel/lectigo/util"
 	"golang.org/x/exp/maps"
 	"google.golang.org/api/calendar/v3"
@@ -26,61 +24,101 @@ type LectioLoginInfo struct {
 	SchoolID string `json:"schoolID"`
 }
 
+// The base URL of the Lectio site
+const DefaultLectioBaseURL = "https://www.lectio.dk/lectio"
+
 type Lectio struct {
+	BaseURL   string // The base URL of Lectio (eg. "https://www.lectio.dk/lectio")
 	Client    *http.Client
-	Collector *colly.Collector
 	LoginInfo *LectioLoginInfo
+	Clock     util.Clock     // The clock used to determine the current week
+	Location  *time.Location // The timezone of the school
+	Logger    *log.Logger
+
+	jar       *sessionJar
+	session   *SessionStore
+	studentID string
 }
 
 type Module struct {
-	Id           string    `json:"id"`        // The ID of the module
-	Title        string    `json:"title"`     // Title of the module (eg. 3a Dansk)
-	StartDate    time.Time `json:"startDate"` // The start date of the module. This includes the date as well as the time of start (eg. 09:55)
-	EndDate      time.Time `json:"endDate"`   // The end date of the module. This includes the date as well as the time of end (eg. 11:25)
-	Room         string    `json:"room"`      // The room of the module (eg. 22)
-	Teacher      string    `json:"teacher"`   // The teacher of the class
-	Homework     string    `json:"homework"`  // Homework for the module
-	ModuleStatus string    `json:"status"`    // The status of the module (eg. "Ændret" or "Aflyst")
+	Id           string    `json:"id"`                // The ID of the module
+	Title        string    `json:"title"`             // Title of the module (eg. 3a Dansk)
+	StartDate    time.Time `json:"startDate"`         // The start date of the module. This includes the date as well as the time of start (eg. 09:55)
+	EndDate      time.Time `json:"endDate"`           // The end date of the module. This includes the date as well as the time of end (eg. 11:25)
+	Room         string    `json:"room"`              // The room of the module (eg. 22)
+	Teacher      string    `json:"teacher"`           // The teacher of the class
+	Homework     string    `json:"homework"`          // Homework for the module
+	Links        []Link    `json:"links,omitempty"`   // Links and documents in the homework. Only found by FillActivities
+	Note         string    `json:"note,omitempty"`    // The note of the module. Only found by FillActivities
+	Content      string    `json:"content,omitempty"` // The other content (Øvrigt indhold) of the module, such as the lesson plan. Only found by FillActivities
+	ModuleStatus string    `json:"status"`            // The status of the module (eg. "Ændret" or "Aflyst")
+
+	activity bool   // Whether the module has an activity page. Private appointments do not
+	version  string // A fingerprint of the module in the schedule, which changes when the module is edited
 }
 
 type AuthenticityToken string
 
-// Creates a new instance of a Lectio struct. Generates a token, if not present in root directory.
-func NewLectio(loginInfo *LectioLoginInfo) (*Lectio, error) {
-	loginUrl := fmt.Sprintf("https://www.lectio.dk/lectio/%s/login.aspx", loginInfo.SchoolID)
-	jar, err := cookiejar.New(nil)
+// Creates a new instance of a Lectio struct and logs in with the given login information.
+// If created with a session store (see WithSession), the saved session is reused when still valid, and the password is only needed when it is not.
+// Returns ErrInvalidCredentials, ErrUnknownSchool, ErrLectioUnavailable or ErrLoginRequired (possibly wrapped) if the login fails
+func NewLectio(loginInfo *LectioLoginInfo, opts ...Option) (*Lectio, error) {
+	o := newOptions(opts)
+	jar, err := newSessionJar()
 	if err != nil {
 		return nil, err
 	}
-	client := &http.Client{Jar: jar}
-	collector := colly.NewCollector(colly.AllowedDomains("lectio.dk", "www.lectio.dk"))
+	client := &http.Client{Jar: jar, CheckRedirect: checkRedirect}
 
-	authToken, err := GetToken(loginUrl, client)
-	if err != nil {
-		return nil, err
+	lectio := &Lectio{
+		BaseURL:   o.baseURL,
+		Client:    client,
+		LoginInfo: loginInfo,
+		Clock:     o.clock,
+		Location:  o.location,
+		Logger:    o.loggerOr("lectio "),
+		jar:       jar,
+		session:   o.session,
 	}
 
-	// Attempts to log the user in with the given login information
-	err = collector.Post(loginUrl, map[string]string{
-		"m$Content$username": loginInfo.Username,
-		"m$Content$password": loginInfo.Password,
-		"__EVENTVALIDATION":  string(*authToken),
-		"__EVENTTARGET":      "m$Content$submitbtn2",
-		"__EVENTARGUMENT":    "",
-		"masterfootervalue":  "X1!ÆØÅ",
-		"LectioPostbackId":   "",
-	})
+	if lectio.session != nil {
+		restored, err := lectio.restoreSession()
+		if err != nil {
+			if errors.Is(err, ErrLectioUnavailable) || errors.Is(err, ErrUnknownSchool) {
+				return nil, err
+			}
+			lectio.Logger.Printf("Could not restore Lectio session, logging in again: %v\n", err)
+		}
+		if restored {
+			return lectio, lectio.SaveSession()
+		}
+		if loginInfo.Password == "" {
+			return nil, ErrLoginRequired
+		}
+	}
 
+	err = lectio.login()
 	if err != nil {
 		return nil, err
 	}
 
-	lectio := &Lectio{
-		Client:    client,
-		Collector: collector,
-		LoginInfo: loginInfo,
+	return lectio, lectio.SaveSession()
+}
+
+// Returns the URL of a page of the school (eg. "SkemaNy.aspx")
+func (l *Lectio) pageURL(page string) string {
+	return fmt.Sprintf("%s/%s/%s", l.BaseURL, l.LoginInfo.SchoolID, page)
+}
+
+// Handle redirects. Lectio redirects to an error page if, for example, the school ID does not exist.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if strings.Contains(req.URL.String(), "fejlhandled") {
+		return ErrUnknownSchool
+	}
+	if len(via) >= 10 {
+		return errors.New("Stopped after 10 redirects")
 	}
-	return lectio, nil
+	return nil
 }
 
 // Converts a Lectio module to a Google Calendar event
@@ -99,16 +137,28 @@ Lektier:
 %s
 	`
 	description := fmt.Sprintf(strings.TrimSpace(descLayout), m.Teacher, m.Homework)
+	if len(m.Links) > 0 {
+		description += "\n\nLinks:"
+		for _, link := range m.Links {
+			description += "\n" + link.String()
+		}
+	}
+	if m.Note != "" {
+		description += "\n\nNote:\n" + m.Note
+	}
+	if m.Content != "" {
+		description += "\n\nØvrigt indhold:\n" + m.Content
+	}
 	return &GoogleEvent{
 		Id:          "lec" + m.Id,
 		Description: description,
 		Start: &calendar.EventDateTime{
 			DateTime: m.StartDate.Format(time.RFC3339),
-			TimeZone: "Europe/Copenhagen",
+			TimeZone: m.StartDate.Location().String(),
 		},
 		End: &calendar.EventDateTime{
 			DateTime: m.EndDate.Format(time.RFC3339),
-			TimeZone: "Europe/Copenhagen",
+			TimeZone: m.EndDate.Location().String(),
 		},
 		Location: m.Room,
 		Summary:  m.Title,
@@ -117,144 +167,63 @@ Lektier:
 	}
 }
 
-// Gets the Lectio schedule of a specified week number.
-func (l *Lectio) GetSchedule(week int) (map[string]Module, error) {
+// Gets the Lectio schedule of a specified ISO week.
+func (l *Lectio) GetSchedule(week util.Week) (map[string]Module, error) {
+	return l.GetTargetSchedule(ScheduleTarget{}, week)
+}
+
+// Gets the Lectio schedule of the target (eg. a teacher or room) of a specified ISO week
+func (l *Lectio) GetTargetSchedule(target ScheduleTarget, week util.Week) (map[string]Module, error) {
+	modules, _, err := l.getSchedule(target, week)
+	return modules, err
+}
+
+// Gets the modules and day events of the Lectio schedule of the target of a specified ISO week
+func (l *Lectio) getSchedule(target ScheduleTarget, week util.Week) (map[string]Module, []DayEvent, error) {
+	if err := target.Validate(); err != nil {
+		return nil, nil, err
+	}
 	startTime := time.Now()
 	modules := make(map[string]Module)
 
-	// Handle redirects. The collector is not expected to get redirected. If it does, it checks for errors - for example the school id does not exist.
-	l.Collector.RedirectHandler = func(req *http.Request, via []*http.Request) error {
-		if strings.Contains(req.URL.String(), "fejlhandled") {
-			return errors.New("Could not get Lectio schedule. The school ID provided does not exist")
-		}
-		return nil
+	scheduleUrl := l.pageURL("SkemaNy.aspx?week=" + week.LectioString() + target.query())
+	response, err := l.Client.Get(scheduleUrl)
+	if err != nil {
+		return nil, nil, requestError(err)
 	}
+	defer response.Body.Close()
 
-	l.Collector.OnHTML("table.s2skema>tbody", func(h *colly.HTMLElement) {
-		var weekStart time.Time
-
-		h.ForEach("tr", func(row int, h *colly.HTMLElement) {
-			// If on day row
-			if row == 1 {
-				var err error
-				weekStartString := h.ChildText("td:nth-child(2)")
-				weekStart, err = parseDate(weekStartString)
-				if err != nil {
-					log.Fatalf("Could not parse date: %v\n", err)
-				}
-
-				h.ForEach("td", func(col int, h *colly.HTMLElement) {
-					// If sidebar column return prematurely
-					if col == 0 {
-						return
-					}
-				})
-			}
-
-			// If on module row
-			if row == 3 {
-				// Foreach column in schedule
-				h.ForEach("td", func(col int, h *colly.HTMLElement) {
-					// If sidebar column return prematurely
-					if col == 0 {
-						return
-					}
-
-					// Gets the current date of the module
-					date := weekStart.AddDate(0, 0, col-1)
-
-					// Foreach module
-					h.ForEach("a.s2skemabrik", func(i int, e *colly.HTMLElement) {
-						addInfo := e.Attr("data-additionalinfo")
-						if addInfo == "" {
-							log.Printf("could not get Lectio schedule for the week %d", week)
-						}
-
-						lines := strings.Split(addInfo, "\n")
-
-						var id, title, teacher, room, homework string
-
-						// Get ID of the module
-						idUrl, _ := url.Parse(e.Attr("href"))
-						urlParams, _ := url.ParseQuery(idUrl.RawQuery)
-						if strings.Contains(idUrl.RawQuery, "absid") {
-							id = urlParams.Get("absid")
-						}
-						if strings.Contains(idUrl.RawQuery, "aftaleid") {
-							id = urlParams.Get("aftaleid")
-							e.ForEach("div.s2skemabrikcontent > span", func(i int, e *colly.HTMLElement) {
-								title = e.Text
-							})
-
-						}
-
-						var status = "uændret"
-						var startDate time.Time
-						var duration time.Duration
-
-						if strings.Contains(addInfo, "Lektier:") {
-							_, homework, _ = strings.Cut(addInfo, "Lektier:")
-							homework = strings.TrimSpace(homework)
-							homework = strings.TrimSuffix(homework, "[...]")
-						}
-
-						for i, line := range lines {
-							if strings.Contains(line, "Hold: ") && title == "" {
-								_, title, _ = strings.Cut(line, ": ")
-								title = strings.TrimSpace(title)
-								continue
-							}
-							if strings.Contains(line, "Lærer: ") {
-								_, teacher, _ = strings.Cut(line, ": ")
-								teacher = strings.TrimSpace(teacher)
-								continue
-							}
-							if strings.Contains(line, "Lokale: ") || strings.Contains(line, "Lokaler: ") {
-								_, room, _ = strings.Cut(line, ": ")
-								room = strings.TrimSpace(room)
-								continue
-							}
-
-							if i == 0 && (strings.Contains(line, "Ændret!") || strings.Contains(line, "Aflyst!")) {
-								status = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(line), "!"))
-								continue
-							}
-
-							if strings.Contains(line, "-") && startDate.IsZero() && duration == 0 {
-								startDate, duration, _ = util.ConvertTimestamp(&date, line)
-								continue
-							}
-						}
-
-						module := Module{
-							Id:           id,
-							Title:        title,
-							StartDate:    startDate,
-							EndDate:      startDate.Add(duration),
-							Room:         room,
-							Teacher:      teacher,
-							Homework:     homework,
-							ModuleStatus: status,
-						}
-						modules[id] = module
-					})
-				})
-			}
-		})
-	})
+	if response.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("Could not get Lectio schedule of %v for week %v: %w", target, week, statusError(response))
+	}
 
-	weekString := fmt.Sprintf("%v%v", week, time.Now().Year())
-	scheduleUrl := fmt.Sprintf("https://www.lectio.dk/lectio/%s/SkemaNy.aspx?week=%v", l.LoginInfo.SchoolID, weekString)
-	err := l.Collector.Visit(scheduleUrl)
+	document, err := goquery.NewDocumentFromReader(response.Body)
 	if err != nil {
-		return nil, err
+		return nil, nil, fmt.Errorf("Could not parse Lectio schedule of %v for week %v: %v", target, week, err)
+	}
+	weekModules, dayEvents, warnings, err := parseScheduleDocument(document, week, l.Location)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not parse Lectio schedule of %v for week %v: %w", target, week, err)
+	}
+	for _, warning := range warnings {
+		l.Logger.Printf("Lectio schedule: %v\n", warning)
 	}
-	fmt.Printf("Got Lectio schedule for week %v in %v\n", week, time.Since(startTime))
-	return modules, nil
+	for _, module := range weekModules {
+		modules[module.Id] = module
+	}
+
+	if target.IsSelf() {
+		l.Logger.Printf("Got Lectio schedule for week %v in %v\n", week, time.Since(startTime))
+	} else {
+		l.Logger.Printf("Got Lectio schedule of %v for week %v in %v\n", target, week, time.Since(startTime))
+	}
+	return modules, dayEvents, nil
 }
 
-// Parses a Lectio date of format "Onsdag (9/12)" to a time.Time struct
-func parseDate(input string) (time.Time, error) {
+// Parses a Lectio date of format "Onsdag (9/12)" to a time.Time struct.
+// Lectio omits the year, so it is taken from the ISO week the date is shown in. A week may span New Year, in which case the date belongs to the year after the monday of the week.
+// The date is at midnight in the input location
+func parseDate(input string, week util.Week, location *time.Location) (time.Time, error) {
 	datePattern := `\((\d+)/(\d+)\)`
 	re := regexp.MustCompile(datePattern)
 
@@ -263,39 +232,68 @@ func parseDate(input string) (time.Time, error) {
 		return time.Time{}, errors.New("Date not found in the input string")
 	}
 
-	currentYear := time.Now().Year()
+	day, _ := strconv.Atoi(match[1])
+	month, _ := strconv.Atoi(match[2])
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}, fmt.Errorf("Invalid date %s/%s in the input string", match[1], match[2])
+	}
 
-	day, _ := time.Parse("2/1", match[1]+"/"+match[2])
-	date := time.Date(currentYear, day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+	monday := week.Monday(location)
+	for _, year := range []int{monday.Year(), monday.Year() + 1} {
+		date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, location)
+		if !date.Before(monday) && date.Before(monday.AddDate(0, 0, 7)) {
+			return date, nil
+		}
+	}
 
-	return date, nil
+	return time.Time{}, fmt.Errorf("Date %d/%d is not in week %v", day, month, week)
 }
 
 // Gets the Lectio schedule from the current weeks and weekCount weeks ahead.
 func (l *Lectio) GetScheduleWeeks(weekCount int) (modules map[string]Module, err error) {
+	modules, _, err = l.GetScheduleWeeksWithDayEvents(weekCount)
+	return modules, err
+}
+
+// Gets the modules and day events of the Lectio schedule from the current week and weekCount weeks ahead.
+// Day events continuing from one week into the next are returned once
+func (l *Lectio) GetScheduleWeeksWithDayEvents(weekCount int) (modules map[string]Module, dayEvents []DayEvent, err error) {
+	return l.GetTargetScheduleWeeks(ScheduleTarget{}, weekCount)
+}
+
+// Gets the modules and day events of the Lectio schedule of the target (eg. a teacher or room) from the current week and weekCount weeks ahead
+func (l *Lectio) GetTargetScheduleWeeks(target ScheduleTarget, weekCount int) (modules map[string]Module, dayEvents []DayEvent, err error) {
 	modules = make(map[string]Module)
-	_, week := time.Now().ISOWeek()
+	week := util.WeekOf(l.Clock.Now().In(l.Location))
 
 	for i := 0; i < weekCount; i++ {
-		weekModules, err := l.GetSchedule(week + i)
+		weekModules, weekDayEvents, err := l.getSchedule(target, week.Add(i))
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		maps.Copy(modules, weekModules)
+		for _, e := range weekDayEvents {
+			dayEvents = addDayEvent(dayEvents, e)
+		}
 	}
 
-	return modules, nil
+	return modules, dayEvents, nil
 }
 
+// Gets the __EVENTVALIDATION token of the Lectio login page, which must be posted along with the login information
 func GetToken(loginUrl string, client *http.Client) (*AuthenticityToken, error) {
 	response, err := client.Get(loginUrl)
 
 	if err != nil {
-		return nil, err
+		return nil, requestError(err)
 	}
 
 	defer response.Body.Close()
 
+	if response.StatusCode != http.StatusOK {
+		return nil, statusError(response)
+	}
+
 	document, err := goquery.NewDocumentFromReader(response.Body)
 	if err != nil {
 		return nil, err
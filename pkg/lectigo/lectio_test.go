@@ -0,0 +1,42 @@
+package lectigo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattismoel/lectigo/util"
+)
+
+func TestParseDate(t *testing.T) {
+	week := util.Week{Year: 2025, Week: 1} // Monday 30/12/2024 to Sunday 5/1/2025
+	tests := []struct {
+		input string
+		want  string // Empty if an error is expected
+	}{
+		{"Mandag (30/12)", "2024-12-30"},
+		{"Tirsdag (31/12)", "2024-12-31"},
+		{"Onsdag (1/1)", "2025-01-01"},
+		{"Fredag (3/1)", "2025-01-03"},
+		{"Søndag (5/1)", "2025-01-05"},
+		{"Mandag (6/1)", ""},
+		{"Søndag (29/12)", ""},
+		{"Mandag (32/1)", ""},
+		{"Mandag", ""},
+	}
+	for _, test := range tests {
+		got, err := parseDate(test.input, week, time.UTC)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("parseDate(%q, %v) = %v, want an error", test.input, week, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseDate(%q, %v) returned error: %v", test.input, week, err)
+			continue
+		}
+		if got := got.Format("2006-01-02"); got != test.want {
+			t.Errorf("parseDate(%q, %v) = %s, want %s", test.input, week, got, test.want)
+		}
+	}
+}
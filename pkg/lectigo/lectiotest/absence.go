@@ -0,0 +1,10 @@
+package lectiotest
+
+import "path"
+
+const absenceDir = "testdata/absence"
+
+// Returns the HTML of the saved absence overview page (fravaerelev.aspx)
+func AbsenceHTML() ([]byte, error) {
+	return testdata.ReadFile(path.Join(absenceDir, "fravaer.html"))
+}
@@ -0,0 +1,10 @@
+package lectiotest
+
+import "path"
+
+const activityDir = "testdata/activity"
+
+// Returns the HTML of the saved activity page (aktivitetforside2.aspx) of the module. Returns an error wrapping fs.ErrNotExist if there is none
+func ActivityHTML(moduleID string) ([]byte, error) {
+	return testdata.ReadFile(path.Join(activityDir, moduleID+".html"))
+}
@@ -0,0 +1,10 @@
+package lectiotest
+
+import "path"
+
+const assignmentsDir = "testdata/assignments"
+
+// Returns the HTML of the saved assignment page (OpgaverElev.aspx)
+func AssignmentsHTML() ([]byte, error) {
+	return testdata.ReadFile(path.Join(assignmentsDir, "opgaver.html"))
+}
@@ -0,0 +1,10 @@
+package lectiotest
+
+import "path"
+
+const entitiesDir = "testdata/entities"
+
+// Returns the HTML of the saved find-schedule page of the type (eg. "laerer"). Returns an error wrapping fs.ErrNotExist if there is none
+func EntitiesHTML(findType string) ([]byte, error) {
+	return testdata.ReadFile(path.Join(entitiesDir, findType+".html"))
+}
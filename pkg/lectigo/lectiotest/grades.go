@@ -0,0 +1,10 @@
+package lectiotest
+
+import "path"
+
+const gradesDir = "testdata/grades"
+
+// Returns the HTML of the saved grade overview page (grade_report.aspx)
+func GradesHTML() ([]byte, error) {
+	return testdata.ReadFile(path.Join(gradesDir, "karakterer.html"))
+}
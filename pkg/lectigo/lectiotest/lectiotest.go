@@ -0,0 +1,61 @@
+// Package lectiotest provides saved Lectio pages and helpers for testing code that reads Lectio.
+package lectiotest
+
+import (
+	"embed"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/mattismoel/lectigo/util"
+)
+
+//go:embed testdata
+var testdata embed.FS
+
+const scheduleDir = "testdata/schedule"
+
+// Schedule fixtures are named after the week they show and the case they cover (eg. "2024-W52-cancelled.html")
+var scheduleFixtureName = regexp.MustCompile(`^(\d{4})-W(\d{2})-([a-z0-9]+)\.html$`)
+
+// A saved Lectio schedule page (SkemaNy.aspx) of a known week
+type ScheduleFixture struct {
+	Name string    // The case the page covers (eg. "cancelled")
+	Week util.Week // The week shown in the page
+	File string    // The name of the page in testdata/schedule (eg. "2024-W52-cancelled.html")
+}
+
+// Returns all saved schedule pages ordered by week
+func ScheduleFixtures() ([]ScheduleFixture, error) {
+	entries, err := fs.ReadDir(testdata, scheduleDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures []ScheduleFixture
+	for _, entry := range entries {
+		matches := scheduleFixtureName.FindStringSubmatch(entry.Name())
+		if len(matches) != 4 {
+			continue
+		}
+		year, _ := strconv.Atoi(matches[1])
+		week, _ := strconv.Atoi(matches[2])
+		fixtures = append(fixtures, ScheduleFixture{
+			Name: matches[3],
+			Week: util.Week{Year: year, Week: week},
+			File: entry.Name(),
+		})
+	}
+
+	sort.Slice(fixtures, func(i, j int) bool {
+		return fixtures[i].File < fixtures[j].File
+	})
+	return fixtures, nil
+}
+
+// Returns the HTML of the saved page
+func (f ScheduleFixture) HTML() ([]byte, error) {
+	return testdata.ReadFile(path.Join(scheduleDir, f.File))
+}
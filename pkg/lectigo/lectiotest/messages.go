@@ -0,0 +1,15 @@
+package lectiotest
+
+import "path"
+
+const messagesDir = "testdata/messages"
+
+// Returns the HTML of the saved message list (beskeder2.aspx)
+func MessagesHTML() ([]byte, error) {
+	return testdata.ReadFile(path.Join(messagesDir, "beskeder.html"))
+}
+
+// Returns the HTML of the saved thread page (beskeder2.aspx?type=showthread) of the thread. Returns an error wrapping fs.ErrNotExist if there is none
+func MessageThreadHTML(threadID string) ([]byte, error) {
+	return testdata.ReadFile(path.Join(messagesDir, "thread-"+threadID+".html"))
+}
@@ -0,0 +1,443 @@
+package lectiotest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattismoel/lectigo/util"
+)
+
+// The name of the session cookie set by Lectio
+const SessionCookie = "ASP.NET_SessionId"
+
+// The name of the long-lived cookie Lectio sets when logging in with "Husk mig" checked. It logs new sessions in automatically
+const AutologinCookie = "autologinkeyV2"
+
+// Shown on the login page when the username or password is wrong
+const LoginFailedMessage = "Fejl i Brugernavn og/eller adgangskode"
+
+// A fake Lectio site for a single school and student, serving the schedule fixtures.
+// Point a lectigo.Lectio at it with lectigo.WithBaseURL(server.BaseURL())
+type Server struct {
+	*httptest.Server
+	SchoolID  string // The only school ID the server knows. Other IDs are redirected to the error page
+	Username  string // The username accepted at login
+	Password  string // The password accepted at login
+	StudentID string // The ID of the logged in student (elevid)
+
+	AutologinLifetime time.Duration // How long autologin cookies last
+
+	mu          sync.Mutex
+	unavailable bool                    // Whether requests fail with 503 Service Unavailable. Set with SetUnavailable
+	sessions    map[string]*fakeSession // Sessions by session cookie value
+	autologins  map[string]time.Time    // Expiry times of autologin keys
+	logins      int
+}
+
+type fakeSession struct {
+	eventValidation string
+	loggedIn        bool
+}
+
+// Creates and starts a fake Lectio server for school 133 accepting the username "elev" with password "hemmelig".
+// The caller should call Close when done
+func NewServer() *Server {
+	s := &Server{
+		SchoolID:  "133",
+		Username:  "elev",
+		Password:  "hemmelig",
+		StudentID: "54321",
+
+		AutologinLifetime: 30 * 24 * time.Hour,
+
+		sessions:   make(map[string]*fakeSession),
+		autologins: make(map[string]time.Time),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Returns the base URL to pass to lectigo.WithBaseURL
+func (s *Server) BaseURL() string {
+	return s.URL + "/lectio"
+}
+
+// Returns the number of successful logins with username and password
+func (s *Server) Logins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+// Ends all sessions, as Lectio does after some time of inactivity. Autologin cookies stay valid
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = make(map[string]*fakeSession)
+}
+
+// Makes every request fail with 503 Service Unavailable while set, as when Lectio is down
+func (s *Server) SetUnavailable(unavailable bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unavailable = unavailable
+}
+
+// Invalidates all autologin cookies, so the next session must log in with username and password
+func (s *Server) ExpireAutologins() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.autologins = make(map[string]time.Time)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	unavailable := s.unavailable
+	s.mu.Unlock()
+	if unavailable {
+		http.Error(w, "Lectio er midlertidigt utilgængelig", http.StatusServiceUnavailable)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "lectio" {
+		http.NotFound(w, r)
+		return
+	}
+	if parts[1] == "fejlhandled.aspx" {
+		writePage(w, http.StatusOK, "Fejl - Lectio", `<div class="ls-error">Der opstod en ukendt fejl</div>`)
+		return
+	}
+	if len(parts) < 3 {
+		http.NotFound(w, r)
+		return
+	}
+
+	schoolID, page := parts[1], strings.Join(parts[2:], "/")
+	if schoolID != s.SchoolID {
+		http.Redirect(w, r, "/lectio/fejlhandled.aspx?title=Skolen+findes+ikke", http.StatusFound)
+		return
+	}
+
+	switch page {
+	case "login.aspx":
+		s.serveLogin(w, r)
+	case "FindSkema.aspx":
+		s.serveFindSchedule(w, r)
+	case "SkemaNy.aspx":
+		s.requireLogin(w, r, s.serveSchedule)
+	case "forside.aspx":
+		s.requireLogin(w, r, s.serveFrontPage)
+	case "OpgaverElev.aspx":
+		s.requireLogin(w, r, s.serveAssignments)
+	case "subnav/fravaerelev.aspx":
+		s.requireLogin(w, r, s.serveAbsence)
+	case "grades/grade_report.aspx":
+		s.requireLogin(w, r, s.serveGrades)
+	case "beskeder2.aspx":
+		s.requireLogin(w, r, s.serveMessages)
+	case "aktivitet/aktivitetforside2.aspx":
+		s.requireLogin(w, r, s.serveActivity)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Returns the session of the request, creating a new one with a session cookie if none exists
+func (s *Server) session(w http.ResponseWriter, r *http.Request) *fakeSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cookie, err := r.Cookie(SessionCookie); err == nil {
+		if session, ok := s.sessions[cookie.Value]; ok {
+			return session
+		}
+	}
+
+	id := randomString()
+	session := &fakeSession{}
+	s.sessions[id] = session
+	http.SetCookie(w, &http.Cookie{Name: SessionCookie, Value: id, Path: "/", HttpOnly: true})
+	return session
+}
+
+func (s *Server) requireLogin(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	session := s.session(w, r)
+	s.mu.Lock()
+	if cookie, err := r.Cookie(AutologinCookie); err == nil && !session.loggedIn {
+		if expires, ok := s.autologins[cookie.Value]; ok && time.Now().Before(expires) {
+			session.loggedIn = true
+		}
+	}
+	loggedIn := session.loggedIn
+	s.mu.Unlock()
+
+	if !loggedIn {
+		http.Redirect(w, r, fmt.Sprintf("/lectio/%s/login.aspx?prevurl=%s", s.SchoolID, r.URL.Path), http.StatusFound)
+		return
+	}
+	next(w, r)
+}
+
+func (s *Server) serveLogin(w http.ResponseWriter, r *http.Request) {
+	session := s.session(w, r)
+
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		valid := r.PostForm.Get("__EVENTVALIDATION") == session.eventValidation &&
+			r.PostForm.Get("m$Content$username") == s.Username &&
+			r.PostForm.Get("m$Content$password") == s.Password
+		if valid {
+			session.loggedIn = true
+			s.logins++
+		}
+		autologin := valid && r.PostForm.Get("m$Content$AutologinCbx") == "on"
+		var autologinKey string
+		var autologinExpires time.Time
+		if autologin {
+			autologinKey = randomString()
+			autologinExpires = time.Now().Add(s.AutologinLifetime)
+			s.autologins[autologinKey] = autologinExpires
+		}
+		s.mu.Unlock()
+
+		if autologin {
+			http.SetCookie(w, &http.Cookie{Name: AutologinCookie, Value: autologinKey, Path: "/", Expires: autologinExpires, HttpOnly: true})
+		}
+		if valid {
+			http.Redirect(w, r, fmt.Sprintf("/lectio/%s/forside.aspx", s.SchoolID), http.StatusFound)
+			return
+		}
+		s.writeLoginForm(w, session, LoginFailedMessage)
+		return
+	}
+
+	s.writeLoginForm(w, session, "")
+}
+
+func (s *Server) writeLoginForm(w http.ResponseWriter, session *fakeSession, errorMessage string) {
+	s.mu.Lock()
+	session.eventValidation = randomString()
+	eventValidation := session.eventValidation
+	s.mu.Unlock()
+
+	var errorMarkup string
+	if errorMessage != "" {
+		errorMarkup = fmt.Sprintf(`<span id="MainContent_ErrorLabel" class="ls-error">%s</span>`, html.EscapeString(errorMessage))
+	}
+	writePage(w, http.StatusOK, "Log ind - Lectio", fmt.Sprintf(`<form method="post" action="./login.aspx" id="aspnetForm">
+<input type="hidden" name="__EVENTVALIDATION" id="__EVENTVALIDATION" value="%s" />
+%s
+<input name="m$Content$username" type="text" id="username" />
+<input name="m$Content$password" type="password" id="password" />
+<input id="m_Content_AutologinCbx" type="checkbox" name="m$Content$AutologinCbx" />
+<a id="m_Content_submitbtn2" href="javascript:__doPostBack('m$Content$submitbtn2','')">Log ind</a>
+</form>`, eventValidation, errorMarkup))
+}
+
+func (s *Server) serveFrontPage(w http.ResponseWriter, r *http.Request) {
+	writePage(w, http.StatusOK, "Forside - Lectio", fmt.Sprintf(
+		`<a id="s_m_HeaderContent_subnavigator_ctl01" href="/lectio/%s/SkemaNy.aspx?type=elev&amp;elevid=%s">Skema</a>`,
+		s.SchoolID, s.StudentID,
+	))
+}
+
+// Serves the saved assignment page to the student
+func (s *Server) serveAssignments(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("elevid") != s.StudentID {
+		http.Error(w, "Unknown student", http.StatusForbidden)
+		return
+	}
+	page, err := AssignmentsHTML()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+// Serves the saved absence overview to the student
+func (s *Server) serveAbsence(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("elevid") != s.StudentID {
+		http.Error(w, "Unknown student", http.StatusForbidden)
+		return
+	}
+	page, err := AbsenceHTML()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+// Serves the saved grade overview to the student
+func (s *Server) serveGrades(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("elevid") != s.StudentID {
+		http.Error(w, "Unknown student", http.StatusForbidden)
+		return
+	}
+	page, err := GradesHTML()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+// Serves the saved message list to the student, or the saved thread with type=showthread. Unknown threads give a page without a thread
+func (s *Server) serveMessages(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("elevid") != s.StudentID {
+		http.Error(w, "Unknown student", http.StatusForbidden)
+		return
+	}
+	var page []byte
+	var err error
+	if r.URL.Query().Get("type") == "showthread" {
+		page, err = MessageThreadHTML(r.URL.Query().Get("id"))
+		if errors.Is(err, fs.ErrNotExist) {
+			writePage(w, http.StatusOK, "Beskeder - Lectio", `<p>Beskeden findes ikke.</p>`)
+			return
+		}
+	} else {
+		page, err = MessagesHTML()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+// Serves the saved find-schedule page of the requested type (eg. laerer). The page is public, so no login is needed
+func (s *Server) serveFindSchedule(w http.ResponseWriter, r *http.Request) {
+	findType := r.URL.Query().Get("type")
+	page, err := EntitiesHTML(findType)
+	if errors.Is(err, fs.ErrNotExist) || findType == "" {
+		writePage(w, http.StatusOK, "Find skema - Lectio", `<div id="m_Content_listecontainer"></div>`)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+// Serves the saved activity page of the requested module, or an activity without homework if there is none
+func (s *Server) serveActivity(w http.ResponseWriter, r *http.Request) {
+	moduleID := r.URL.Query().Get("absid")
+	page, err := ActivityHTML(moduleID)
+	if errors.Is(err, fs.ErrNotExist) || moduleID == "" {
+		writePage(w, http.StatusOK, "Aktivitet - Lectio", `<div id="s_m_Content_Content_tocAndToolbar_ActivityHeader"><h1>Aktivitet</h1></div>`)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+// Serves the fixture of the requested week, or an empty schedule if there is none
+func (s *Server) serveSchedule(w http.ResponseWriter, r *http.Request) {
+	week, err := parseLectioWeek(r.URL.Query().Get("week"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fixtures, err := ScheduleFixtures()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, fixture := range fixtures {
+		if fixture.Week != week {
+			continue
+		}
+		page, err := fixture.HTML()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+		return
+	}
+
+	writePage(w, http.StatusOK, "Skema - Lectio", emptySchedule(week))
+}
+
+// Parses Lectio's week query parameter (eg. "012025")
+func parseLectioWeek(s string) (util.Week, error) {
+	if len(s) != 6 {
+		return util.Week{}, fmt.Errorf("Invalid week %q", s)
+	}
+	week, err := strconv.Atoi(s[:2])
+	if err != nil {
+		return util.Week{}, fmt.Errorf("Invalid week %q", s)
+	}
+	year, err := strconv.Atoi(s[2:])
+	if err != nil {
+		return util.Week{}, fmt.Errorf("Invalid week %q", s)
+	}
+	return util.Week{Year: year, Week: week}, nil
+}
+
+// Returns a schedule table of the week with no modules
+func emptySchedule(week util.Week) string {
+	days := []string{"Mandag", "Tirsdag", "Onsdag", "Torsdag", "Fredag"}
+	monday := week.Monday(time.UTC)
+
+	var headers, infos, modules strings.Builder
+	for i, day := range days {
+		date := monday.AddDate(0, 0, i)
+		fmt.Fprintf(&headers, `<td class="s2dayHeader"><span class="s2dayHeaderText">%s (%d/%d)</span></td>`, day, date.Day(), int(date.Month()))
+		infos.WriteString(`<td class="s2infoHeader s2skemabrikcontainer"></td>`)
+		modules.WriteString(`<td class="s2skemabrikcontainer"></td>`)
+	}
+
+	return fmt.Sprintf(`<table class="s2skema">
+<tbody>
+<tr class="s2weekHeader"><td colspan="6"><span>Uge %d - %d</span></td></tr>
+<tr><td class="s2module-bg"></td>%s</tr>
+<tr><td class="s2infoHeader"></td>%s</tr>
+<tr><td class="s2module-bg"></td>%s</tr>
+</tbody>
+</table>`, week.Week, week.Year, headers.String(), infos.String(), modules.String())
+}
+
+func writePage(w http.ResponseWriter, status int, title, body string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html lang=\"da\">\n<head>\n<meta charset=\"utf-8\" />\n<title>%s</title>\n</head>\n<body>\n%s\n</body>\n</html>\n", html.EscapeString(title), body)
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
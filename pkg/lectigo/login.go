@@ -0,0 +1,110 @@
+package lectigo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("Invalid Lectio username or password")          // Returned when Lectio rejects the login information
+	ErrUnknownSchool      = errors.New("The Lectio school ID provided does not exist") // Returned when Lectio redirects to its error page, as the school does not exist
+	ErrLectioUnavailable  = errors.New("Lectio is unavailable")                        // Returned when Lectio cannot be reached or fails to respond
+)
+
+// Shown on the Lectio login page when the username or password is wrong
+const loginFailedMarkup = "Fejl i Brugernavn og/eller adgangskode"
+
+// The cookie holding the Lectio session
+const sessionCookieName = "ASP.NET_SessionId"
+
+// Logs in to Lectio with the login information of the Lectio struct.
+// The login is checked by the response, which must not be the login page again, and by the session cookie Lectio sets
+func (l *Lectio) login() error {
+	loginUrl := l.pageURL("login.aspx")
+
+	authToken, err := GetToken(loginUrl, l.Client)
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{
+		"m$Content$username": {l.LoginInfo.Username},
+		"m$Content$password": {l.LoginInfo.Password},
+		"__EVENTVALIDATION":  {string(*authToken)},
+		"__EVENTTARGET":      {"m$Content$submitbtn2"},
+		"__EVENTARGUMENT":    {""},
+		"masterfootervalue":  {"X1!ÆØÅ"},
+		"LectioPostbackId":   {""},
+	}
+	if l.session != nil {
+		// Makes Lectio set the long-lived autologin cookie, which is saved with the session
+		form.Set("m$Content$AutologinCbx", "on")
+	}
+
+	// Attempts to log the user in with the given login information
+	response, err := l.Client.PostForm(loginUrl, form)
+	if err != nil {
+		return requestError(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return statusError(response)
+	}
+
+	document, err := goquery.NewDocumentFromReader(response.Body)
+	if err != nil {
+		return fmt.Errorf("%w: could not read login response: %v", ErrLectioUnavailable, err)
+	}
+
+	if strings.Contains(document.Text(), loginFailedMarkup) {
+		return ErrInvalidCredentials
+	}
+	if document.Find("input[name='m$Content$password']").Length() > 0 {
+		// Lectio showed the login page again without telling why
+		return ErrInvalidCredentials
+	}
+	if !l.hasSessionCookie() {
+		return fmt.Errorf("%w: no session cookie was set at login", ErrLectioUnavailable)
+	}
+
+	return nil
+}
+
+// Reports whether the cookie jar holds a Lectio session cookie
+func (l *Lectio) hasSessionCookie() bool {
+	if l.Client.Jar == nil {
+		return false
+	}
+	u, err := url.Parse(l.pageURL(""))
+	if err != nil {
+		return false
+	}
+	for _, cookie := range l.Client.Jar.Cookies(u) {
+		if cookie.Name == sessionCookieName && cookie.Value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Converts an error of a request to Lectio to one of the typed Lectio errors
+func requestError(err error) error {
+	if errors.Is(err, ErrUnknownSchool) {
+		return ErrUnknownSchool
+	}
+	return fmt.Errorf("%w: %v", ErrLectioUnavailable, err)
+}
+
+// Returns an error describing an unexpected HTTP status of a Lectio response
+func statusError(response *http.Response) error {
+	if response.StatusCode >= 500 {
+		return fmt.Errorf("%w: %s", ErrLectioUnavailable, response.Status)
+	}
+	return fmt.Errorf("Unexpected response from Lectio: %s", response.Status)
+}
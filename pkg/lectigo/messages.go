@@ -0,0 +1,260 @@
+package lectigo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Returned when a page does not contain a Lectio message list or thread
+var (
+	ErrMessagesNotFound = errors.New("Could not find a message list in the Lectio page")
+	ErrThreadNotFound   = errors.New("Could not find a message thread in the Lectio page")
+)
+
+// The folder of the newest messages (Nyeste)
+const newestMessagesFolder = "-70"
+
+// The ID of a thread in the postback of its row (eg. "__doPostBack('__Page','$LB2$_MC_$_61000800')")
+var threadPostback = regexp.MustCompile(`_MC_\$_(\d+)`)
+
+// A thread of messages (beskeder)
+type MessageThread struct {
+	Id       string    `json:"id"`                 // The ID of the thread
+	Subject  string    `json:"subject"`            // The subject of the thread
+	From     string    `json:"from"`               // The sender of the first message
+	To       string    `json:"to"`                 // The receivers of the first message
+	Latest   time.Time `json:"latest"`             // The time of the latest message in the thread
+	Unread   bool      `json:"unread"`             // Whether the thread has messages the user has not read
+	Messages []Message `json:"messages,omitempty"` // The messages of the thread, oldest first. Only filled by GetMessageThread
+}
+
+// A single message of a thread
+type Message struct {
+	From    string    `json:"from"`
+	Time    time.Time `json:"time"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`            // The message as plain text
+	Links   []Link    `json:"links,omitempty"` // The links and attached documents of the message
+}
+
+// Describes the thread in one line (eg. "17/01 14:32 Kasper Larsen: Ekskursion til Berlin")
+func (t MessageThread) String() string {
+	return fmt.Sprintf("%s %s: %s", t.Latest.Format("02/01 15:04"), t.From, t.Subject)
+}
+
+// Parses a Lectio message list page (beskeder2.aspx). Lectio shows the time of messages from today without a date,
+// and messages from this week with a weekday, so times are read relative to now in the input location.
+// Columns are found by their header. Threads are returned in the order they appear in the page.
+// Rows without a thread ID or a readable time are skipped and returned as warnings, while an error is returned if the message list itself cannot be read
+func ParseMessages(r io.Reader, now time.Time, location *time.Location) ([]MessageThread, []string, error) {
+	document, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	table := document.Find("table[id$='threadGV_ctl00']").First()
+	if table.Length() == 0 {
+		return nil, nil, ErrMessagesNotFound
+	}
+
+	var headers []string
+	table.Find("tr").First().Find("th").Each(func(i int, s *goquery.Selection) {
+		headers = append(headers, cellText(s))
+	})
+
+	var threads []MessageThread
+	var warnings []string
+	table.Find("tr").Each(func(row int, tr *goquery.Selection) {
+		cells := tr.Find("td")
+		if cells.Length() == 0 {
+			return
+		}
+
+		t := MessageThread{Unread: tr.HasClass("unread")}
+		var problem error
+		cells.Each(func(col int, s *goquery.Selection) {
+			if col >= len(headers) {
+				return
+			}
+			switch headers[col] {
+			case "Emne":
+				t.Subject = cellText(s)
+				t.Id = threadID(s)
+			case "Fra":
+				t.From = cellText(s)
+			case "Til":
+				t.To = cellText(s)
+			case "Seneste ændring":
+				t.Latest, problem = parseMessageTime(cellText(s), now, location)
+			}
+		})
+		if t.Id == "" {
+			problem = errors.New("no thread ID found")
+		}
+		if problem != nil {
+			warnings = append(warnings, fmt.Sprintf("row %d: %v", row, problem))
+			return
+		}
+		threads = append(threads, t)
+	})
+
+	return threads, warnings, nil
+}
+
+// Returns the ID of the thread linked to in the subject cell, either by the id query parameter of the link or by its postback
+func threadID(s *goquery.Selection) string {
+	a := s.Find("a").First()
+	if href, ok := a.Attr("href"); ok {
+		if u, err := url.Parse(href); err == nil && u.Query().Get("id") != "" {
+			return u.Query().Get("id")
+		}
+	}
+	onclick, _ := a.Attr("onclick")
+	if matches := threadPostback.FindStringSubmatch(onclick); len(matches) == 2 {
+		return matches[1]
+	}
+	return ""
+}
+
+// Parses the time of a message as Lectio shows it: "17/01-2025 14:32", "17/01-2025", "fr 17/01 14:32" (this year) or "14:32" (today)
+func parseMessageTime(s string, now time.Time, location *time.Location) (time.Time, error) {
+	now = now.In(location)
+	for _, layout := range []string{"02/01-2006 15:04", "02/01-2006"} {
+		if t, err := time.ParseInLocation(layout, s, location); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.ParseInLocation("15:04", s, location); err == nil {
+		return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, location), nil
+	}
+	if _, dayTime, ok := strings.Cut(s, " "); ok {
+		if t, err := time.ParseInLocation("02/01 15:04", dayTime, location); err == nil {
+			year := now.Year()
+			// A message from late December read in early January is from last year
+			if t.Month() > now.Month() {
+				year--
+			}
+			return time.Date(year, t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, location), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Invalid message time %q", s)
+}
+
+// Parses a Lectio message thread page (beskeder2.aspx?type=showthread). Times are read relative to now in the input location, as in ParseMessages.
+// The returned thread only has the fields found in the page: the ID, the subject, the sender and receivers and the messages
+func ParseMessageThread(r io.Reader, now time.Time, location *time.Location) (*MessageThread, error) {
+	document, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	list := document.Find("[id$='ThreadList']").First()
+	if list.Length() == 0 {
+		return nil, ErrThreadNotFound
+	}
+
+	t := &MessageThread{}
+	t.Id, _ = list.Attr("data-threadid")
+	t.Subject = cellText(document.Find("[id$='ThreadSubject']").First())
+
+	var errs []error
+	list.Find("li.message-thread-message").Each(func(i int, li *goquery.Selection) {
+		content := li.Find(".message-thread-message-content").First()
+		m := Message{
+			From:    cellText(li.Find(".message-thread-message-sender").First()),
+			Subject: cellText(li.Find(".message-thread-message-header").First()),
+			Body:    htmlText(content),
+			Links:   parseLinks(content),
+		}
+		sent, err := parseMessageTime(cellText(li.Find(".message-thread-message-date").First()), now, location)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Message %d: %v", i+1, err))
+		}
+		m.Time = sent
+		t.Messages = append(t.Messages, m)
+	})
+
+	if len(t.Messages) > 0 {
+		first, last := t.Messages[0], t.Messages[len(t.Messages)-1]
+		t.From = first.From
+		t.Latest = last.Time
+		if t.Subject == "" {
+			t.Subject = first.Subject
+		}
+	}
+	t.To = cellText(document.Find("[id$='ThreadReceivers']").First())
+
+	return t, errors.Join(errs...)
+}
+
+// Gets the newest message threads of the logged in user, read and unread
+func (l *Lectio) GetMessages() ([]MessageThread, error) {
+	studentID, err := l.StudentID()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := l.Client.Get(l.pageURL("beskeder2.aspx?type=&elevid=" + url.QueryEscape(studentID) + "&selectedfolderid=" + newestMessagesFolder))
+	if err != nil {
+		return nil, requestError(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Could not get Lectio messages: %w", statusError(response))
+	}
+
+	threads, warnings, err := ParseMessages(response.Body, l.Clock.Now(), l.Location)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse Lectio messages: %w", err)
+	}
+	for _, warning := range warnings {
+		l.Logger.Printf("Lectio messages: %s\n", warning)
+	}
+	return threads, nil
+}
+
+// Gets a message thread with all its messages. Lectio marks the thread as read
+func (l *Lectio) GetMessageThread(threadID string) (*MessageThread, error) {
+	studentID, err := l.StudentID()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := l.Client.Get(l.pageURL("beskeder2.aspx?type=showthread&elevid=" + url.QueryEscape(studentID) + "&selectedfolderid=" + newestMessagesFolder + "&id=" + url.QueryEscape(threadID)))
+	if err != nil {
+		return nil, requestError(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Could not get Lectio message thread %v: %w", threadID, statusError(response))
+	}
+
+	thread, err := ParseMessageThread(response.Body, l.Clock.Now(), l.Location)
+	if errors.Is(err, ErrThreadNotFound) {
+		return nil, fmt.Errorf("Could not find Lectio message thread %v", threadID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse Lectio message thread %v: %v", threadID, err)
+	}
+	if thread.Id == "" {
+		thread.Id = threadID
+	}
+	for _, m := range thread.Messages {
+		for i, link := range m.Links {
+			if u, err := response.Request.URL.Parse(link.URL); err == nil {
+				m.Links[i].URL = u.String()
+			}
+		}
+	}
+	return thread, nil
+}
@@ -0,0 +1,70 @@
+package lectigo_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+)
+
+func TestParseMessages(t *testing.T) {
+	location := copenhagen(t)
+	// Lectio shows the time of recent messages relative to the day they are shown
+	now := time.Date(2025, time.January, 22, 12, 0, 0, 0, location)
+
+	var golden struct {
+		Threads []lectigo.MessageThread  `json:"threads"` // The message list
+		Details []*lectigo.MessageThread `json:"details"` // The saved threads, ordered by ID
+	}
+	threads, warnings, err := lectigo.ParseMessages(bytes.NewReader(readTestdata(t, "messages", "beskeder.html")), now, location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Got warnings %q of the saved page", warnings)
+	}
+	golden.Threads = threads
+	for _, id := range []string{"61000800", "61000803"} {
+		thread, err := lectigo.ParseMessageThread(bytes.NewReader(readTestdata(t, "messages", "thread-"+id+".html")), now, location)
+		if err != nil {
+			t.Fatalf("Could not parse thread %s: %v", id, err)
+		}
+		golden.Details = append(golden.Details, thread)
+	}
+	checkGolden(t, golden, "messages", "messages.golden.json")
+}
+
+// A message list with a valid thread between a thread with a bad time and a thread with no link
+const badMessageRows = `<table id="s_m_Content_Content_threadGV_ctl00">
+<tr><th>Emne</th><th>Fra</th><th>Seneste ændring</th></tr>
+<tr><td><a href="beskeder2.aspx?type=showthread&amp;id=61000810">Skemaændring</a></td><td>Mette Holm (MH)</td><td>i går</td></tr>
+<tr><td><a href="beskeder2.aspx?type=showthread&amp;id=61000811">Fysikrapport</a></td><td>Mette Holm (MH)</td><td>10:05</td></tr>
+<tr><td>Slettet besked</td><td>Rektor</td><td>17/01-2025 15:45</td></tr>
+</table>`
+
+func TestParseMessagesBadRows(t *testing.T) {
+	location := copenhagen(t)
+	threads, warnings, err := lectigo.ParseMessages(strings.NewReader(badMessageRows), time.Date(2025, time.January, 22, 12, 0, 0, 0, location), location)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the bad rows are skipped, so the other threads are still notified about
+	if len(threads) != 1 || threads[0].Id != "61000811" || !threads[0].Latest.Equal(time.Date(2025, time.January, 22, 10, 5, 0, 0, location)) {
+		t.Errorf("Got threads %+v, want only 61000811 at 10:05 today", threads)
+	}
+	if len(warnings) != 2 || !strings.HasPrefix(warnings[0], "row 1:") || !strings.HasPrefix(warnings[1], "row 3:") {
+		t.Errorf("Got warnings %q, want one for row 1 and one for row 3", warnings)
+	}
+}
+
+func TestParseMessagesNotFound(t *testing.T) {
+	location := copenhagen(t)
+	_, _, err := lectigo.ParseMessages(strings.NewReader("<html><body><p>Log ind</p></body></html>"), time.Date(2025, time.January, 22, 12, 0, 0, 0, location), location)
+	if !errors.Is(err, lectigo.ErrMessagesNotFound) {
+		t.Errorf("Got error %v, want %v", err, lectigo.ErrMessagesNotFound)
+	}
+}
@@ -0,0 +1,242 @@
+package lectigo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Sends notifications about changes to modules
+type Notifier interface {
+	Notify(changes []ModuleChange) error
+}
+
+// Sends notifications about new Lectio messages
+type MessageNotifier interface {
+	NotifyMessages(threads []MessageThread) error
+}
+
+// The data notification templates are executed with. Changes is set in notifications about changes, and Messages in notifications about new messages
+type NotificationData struct {
+	Changes  []ModuleChange
+	Messages []MessageThread
+}
+
+// The default templates of notifications
+const (
+	DefaultNotificationTitle = `{{len .Changes}} change{{if ne (len .Changes) 1}}s{{end}} to the Lectio schedule`
+	DefaultNotificationBody  = `{{range .Changes}}{{.}}
+{{end}}`
+	DefaultMessageNotificationTitle = `{{len .Messages}} new Lectio message{{if ne (len .Messages) 1}}s{{end}}`
+	DefaultMessageNotificationBody  = `{{range .Messages}}{{.}}
+{{end}}`
+)
+
+// Functions available in notification templates, besides the built-in ones. json encodes a value as JSON
+var NotificationFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Parses a notification template, using the default if the text is empty
+func parseNotificationTemplate(name, text, defaultText string) (*template.Template, error) {
+	if text == "" {
+		text = defaultText
+	}
+	if text == "" {
+		return nil, nil
+	}
+	t, err := template.New(name).Funcs(NotificationFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse %s template: %v", name, err)
+	}
+	return t, nil
+}
+
+// Posts changes to a URL as JSON. The body is {"changes": [...]} unless a template is given
+type WebhookNotifier struct {
+	URL      string
+	Template *template.Template // The template of the request body. Nil posts the changes as JSON
+	Client   *http.Client
+}
+
+// Creates a webhook notifier posting to the URL. An empty template posts the changes as JSON
+func NewWebhookNotifier(url, body string) (*WebhookNotifier, error) {
+	t, err := parseNotificationTemplate("webhook", body, "")
+	if err != nil {
+		return nil, err
+	}
+	return &WebhookNotifier{URL: url, Template: t, Client: http.DefaultClient}, nil
+}
+
+func (n *WebhookNotifier) Notify(changes []ModuleChange) error {
+	var body []byte
+	var err error
+	if n.Template != nil {
+		var s string
+		s, err = execute(n.Template, NotificationData{Changes: changes})
+		body = []byte(s)
+	} else {
+		body, err = json.Marshal(map[string]any{"changes": changes})
+	}
+	if err != nil {
+		return fmt.Errorf("Could not create webhook body: %v", err)
+	}
+
+	return post(n.Client, n.URL, "application/json", body, nil)
+}
+
+// Posts the new messages as JSON: {"messages": [...]}. The template is only used for changes
+func (n *WebhookNotifier) NotifyMessages(threads []MessageThread) error {
+	body, err := json.Marshal(map[string]any{"messages": threads})
+	if err != nil {
+		return fmt.Errorf("Could not create webhook body: %v", err)
+	}
+	return post(n.Client, n.URL, "application/json", body, nil)
+}
+
+// Sends changes as a plain text email over SMTP
+type EmailNotifier struct {
+	Addr    string    // The address of the SMTP server (eg. "smtp.example.com:587"). STARTTLS is used if the server supports it
+	Auth    smtp.Auth // Nil sends without authentication
+	From    string
+	To      []string
+	Subject *template.Template
+	Body    *template.Template
+
+	MessageSubject *template.Template // The subject of notifications about new messages
+	MessageBody    *template.Template // The body of notifications about new messages
+}
+
+// Creates an email notifier. Empty templates use the defaults
+func NewEmailNotifier(addr string, auth smtp.Auth, from string, to []string, subject, body string) (*EmailNotifier, error) {
+	subjectTemplate, err := parseNotificationTemplate("subject", subject, DefaultNotificationTitle)
+	if err != nil {
+		return nil, err
+	}
+	bodyTemplate, err := parseNotificationTemplate("body", body, DefaultNotificationBody)
+	if err != nil {
+		return nil, err
+	}
+	n := &EmailNotifier{Addr: addr, Auth: auth, From: from, To: to, Subject: subjectTemplate, Body: bodyTemplate}
+	n.MessageSubject = template.Must(parseNotificationTemplate("subject", "", DefaultMessageNotificationTitle))
+	n.MessageBody = template.Must(parseNotificationTemplate("body", "", DefaultMessageNotificationBody))
+	return n, nil
+}
+
+func (n *EmailNotifier) Notify(changes []ModuleChange) error {
+	return n.send(n.Subject, n.Body, NotificationData{Changes: changes})
+}
+
+func (n *EmailNotifier) NotifyMessages(threads []MessageThread) error {
+	return n.send(n.MessageSubject, n.MessageBody, NotificationData{Messages: threads})
+}
+
+// Sends an email with the subject and body templates executed with the data
+func (n *EmailNotifier) send(subjectTemplate, bodyTemplate *template.Template, data NotificationData) error {
+	subject, err := execute(subjectTemplate, data)
+	if err != nil {
+		return fmt.Errorf("Could not create email subject: %v", err)
+	}
+	body, err := execute(bodyTemplate, data)
+	if err != nil {
+		return fmt.Errorf("Could not create email body: %v", err)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	msg.WriteString("\r\n")
+
+	err = smtp.SendMail(n.Addr, n.Auth, n.From, n.To, []byte(msg.String()))
+	if err != nil {
+		return fmt.Errorf("Could not send email: %v", err)
+	}
+	return nil
+}
+
+// Posts changes as plain text to a push endpoint (eg. an ntfy topic), with the title in the Title header
+type PushNotifier struct {
+	URL    string
+	Title  *template.Template
+	Body   *template.Template
+	Client *http.Client
+
+	MessageTitle *template.Template // The title of notifications about new messages
+	MessageBody  *template.Template // The body of notifications about new messages
+}
+
+// Creates a push notifier posting to the URL. Empty templates use the defaults
+func NewPushNotifier(url, title, body string) (*PushNotifier, error) {
+	titleTemplate, err := parseNotificationTemplate("title", title, DefaultNotificationTitle)
+	if err != nil {
+		return nil, err
+	}
+	bodyTemplate, err := parseNotificationTemplate("body", body, DefaultNotificationBody)
+	if err != nil {
+		return nil, err
+	}
+	n := &PushNotifier{URL: url, Title: titleTemplate, Body: bodyTemplate, Client: http.DefaultClient}
+	n.MessageTitle = template.Must(parseNotificationTemplate("title", "", DefaultMessageNotificationTitle))
+	n.MessageBody = template.Must(parseNotificationTemplate("body", "", DefaultMessageNotificationBody))
+	return n, nil
+}
+
+func (n *PushNotifier) Notify(changes []ModuleChange) error {
+	return n.send(n.Title, n.Body, NotificationData{Changes: changes})
+}
+
+func (n *PushNotifier) NotifyMessages(threads []MessageThread) error {
+	return n.send(n.MessageTitle, n.MessageBody, NotificationData{Messages: threads})
+}
+
+// Posts the title and body templates executed with the data
+func (n *PushNotifier) send(titleTemplate, bodyTemplate *template.Template, data NotificationData) error {
+	title, err := execute(titleTemplate, data)
+	if err != nil {
+		return fmt.Errorf("Could not create push title: %v", err)
+	}
+	body, err := execute(bodyTemplate, data)
+	if err != nil {
+		return fmt.Errorf("Could not create push body: %v", err)
+	}
+
+	header := http.Header{"Title": {mime.QEncoding.Encode("utf-8", title)}}
+	return post(n.Client, n.URL, "text/plain; charset=utf-8", []byte(body), header)
+}
+
+// Posts the body to the URL, failing if the response is not a success
+func post(client *http.Client, url, contentType string, body []byte, header http.Header) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	response, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Could not post notification to %s: %v", url, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("Could not post notification to %s: %s", url, response.Status)
+	}
+	return nil
+}
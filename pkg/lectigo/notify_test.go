@@ -0,0 +1,199 @@
+package lectigo_test
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/pkg/lectigo/notifytest"
+)
+
+// Returns a room change and a cancellation of modules on 10 December 2024
+func testChanges(t *testing.T) []lectigo.ModuleChange {
+	previous := testModule(t, "61000101", 10)
+	moved := previous
+	moved.Room = "14"
+	cancelled := testModule(t, "61000102", 10)
+	cancelled.StartDate = cancelled.StartDate.Add(2 * time.Hour)
+	cancelled.EndDate = cancelled.EndDate.Add(2 * time.Hour)
+	wasCancelled := cancelled
+	cancelled.ModuleStatus = "aflyst"
+	return []lectigo.ModuleChange{
+		*lectigo.NewModuleChange(&previous, &moved),
+		*lectigo.NewModuleChange(&wasCancelled, &cancelled),
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	receiver := notifytest.NewHTTPReceiver()
+	defer receiver.Close()
+	changes := testChanges(t)
+
+	n, err := lectigo.NewWebhookNotifier(receiver.URL+"/hook", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(changes); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := receiver.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("Got %d requests, want 1", len(requests))
+	}
+	r := requests[0]
+	if r.Method != http.MethodPost || r.Path != "/hook" || r.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Got %s %s with content type %q, want a JSON POST to /hook", r.Method, r.Path, r.Header.Get("Content-Type"))
+	}
+
+	var payload struct {
+		Changes []struct {
+			Module   map[string]any `json:"module"`
+			Previous map[string]any `json:"previous"`
+			Fields   []string       `json:"fields"`
+		} `json:"changes"`
+	}
+	if err := json.Unmarshal(r.Body, &payload); err != nil {
+		t.Fatalf("Could not decode payload %s: %v", r.Body, err)
+	}
+	if len(payload.Changes) != 2 {
+		t.Fatalf("Got %d changes in the payload, want 2", len(payload.Changes))
+	}
+	moved := payload.Changes[0]
+	if !reflect.DeepEqual(moved.Fields, []string{lectigo.ChangedRoom}) || moved.Module["room"] != "14" || moved.Previous["room"] != "22" || moved.Module["id"] != "61000101" {
+		t.Errorf("Got room change %+v, want room 22 -> 14 of 61000101", moved)
+	}
+	cancelled := payload.Changes[1]
+	if !reflect.DeepEqual(cancelled.Fields, []string{lectigo.ChangedStatus}) || cancelled.Module["status"] != "aflyst" {
+		t.Errorf("Got cancellation %+v, want the status aflyst", cancelled)
+	}
+	if cancelled.Module["startDate"] != "2024-12-10T10:15:00+01:00" {
+		t.Errorf("Got start date %v, want 2024-12-10T10:15:00+01:00", cancelled.Module["startDate"])
+	}
+}
+
+func TestWebhookNotifierTemplate(t *testing.T) {
+	receiver := notifytest.NewHTTPReceiver()
+	defer receiver.Close()
+
+	n, err := lectigo.NewWebhookNotifier(receiver.URL, `{"text": {{range .Changes}}{{json .String}}{{end}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(testChanges(t)[:1]); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"text": "3a Da 10/12 08:15: room 22 -\u003e 14"}`
+	if got := string(receiver.Requests()[0].Body); got != want {
+		t.Errorf("Got body %s, want %s", got, want)
+	}
+}
+
+func TestWebhookNotifierError(t *testing.T) {
+	receiver := notifytest.NewHTTPReceiver()
+	defer receiver.Close()
+	receiver.Status = http.StatusInternalServerError
+
+	n, err := lectigo.NewWebhookNotifier(receiver.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(testChanges(t)); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Got error %v, want the 500 status", err)
+	}
+}
+
+func TestPushNotifier(t *testing.T) {
+	receiver := notifytest.NewHTTPReceiver()
+	defer receiver.Close()
+
+	n, err := lectigo.NewPushNotifier(receiver.URL+"/lectio", "{{len .Changes}} ændringer", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(testChanges(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	r := receiver.Requests()[0]
+	title, err := new(mime.WordDecoder).DecodeHeader(r.Header.Get("Title"))
+	if err != nil || title != "2 ændringer" {
+		t.Errorf("Got title %q (%v), want %q", title, err, "2 ændringer")
+	}
+	want := "3a Da 10/12 08:15: room 22 -> 14\n3a Da 10/12 10:15: aflyst"
+	if string(r.Body) != want {
+		t.Errorf("Got body %q, want %q", r.Body, want)
+	}
+}
+
+func TestEmailNotifier(t *testing.T) {
+	server, err := notifytest.NewSMTPServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	auth := smtp.PlainAuth("", "lectigo", "hemmelig", "127.0.0.1")
+	n, err := lectigo.NewEmailNotifier(server.Addr(), auth, "lectigo@example.com", []string{"elev@example.com", "forælder@example.com"}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(testChanges(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("Got %d emails, want 1", len(messages))
+	}
+	m := messages[0]
+	if m.From != "lectigo@example.com" || !reflect.DeepEqual(m.To, []string{"elev@example.com", "forælder@example.com"}) || m.Username != "lectigo" {
+		t.Errorf("Got email from %s to %v as %q, want from lectigo@example.com to both receivers as lectigo", m.From, m.To, m.Username)
+	}
+
+	header, body, ok := strings.Cut(m.Data, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("Email has no body: %q", m.Data)
+	}
+	for _, line := range []string{
+		"From: lectigo@example.com",
+		"To: elev@example.com, forælder@example.com",
+		"Subject: 2 changes to the Lectio schedule",
+		"Content-Type: text/plain; charset=utf-8",
+	} {
+		if !strings.Contains(header, line+"\r\n") {
+			t.Errorf("Email header has no line %q:\n%s", line, header)
+		}
+	}
+	want := "3a Da 10/12 08:15: room 22 -> 14\r\n3a Da 10/12 10:15: aflyst\r\n"
+	if body != want {
+		t.Errorf("Got body %q, want %q", body, want)
+	}
+}
+
+func TestEmailNotifierMessages(t *testing.T) {
+	server, err := notifytest.NewSMTPServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	n, err := lectigo.NewEmailNotifier(server.Addr(), nil, "lectigo@example.com", []string{"elev@example.com"}, "Ændringer", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	thread := lectigo.MessageThread{Id: "61000800", Subject: "Ekskursion til Berlin", From: "Kasper Larsen", Latest: testModule(t, "1", 17).StartDate}
+	if err := n.NotifyMessages([]lectigo.MessageThread{thread}); err != nil {
+		t.Fatal(err)
+	}
+	data := server.Messages()[0].Data
+	if !strings.Contains(data, "Subject: 1 new Lectio message\r\n") || !strings.Contains(data, "17/12 08:15 Kasper Larsen: Ekskursion til Berlin\r\n") {
+		t.Errorf("Got email %q, want the message notification", data)
+	}
+}
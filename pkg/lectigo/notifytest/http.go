@@ -0,0 +1,51 @@
+// Package notifytest provides fake HTTP and SMTP receivers for testing the notifiers of lectigo locally.
+package notifytest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// A request received by an HTTPReceiver
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// An HTTP server recording the requests it receives, for webhook and push notifiers
+type HTTPReceiver struct {
+	*httptest.Server
+	Status int // The status returned for requests. Defaults to 200 OK
+
+	mu       sync.Mutex
+	requests []Request
+}
+
+// Creates and starts an HTTP receiver. The caller should call Close when done
+func NewHTTPReceiver() *HTTPReceiver {
+	r := &HTTPReceiver{Status: http.StatusOK}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.serveHTTP))
+	return r
+}
+
+func (r *HTTPReceiver) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	r.requests = append(r.requests, Request{Method: req.Method, Path: req.URL.Path, Header: req.Header.Clone(), Body: body})
+	status := r.Status
+	r.mu.Unlock()
+
+	w.WriteHeader(status)
+}
+
+// Returns the requests received so far
+func (r *HTTPReceiver) Requests() []Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Request(nil), r.requests...)
+}
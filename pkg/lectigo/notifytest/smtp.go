@@ -0,0 +1,148 @@
+package notifytest
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// An email received by an SMTPServer
+type Message struct {
+	From     string
+	To       []string
+	Username string // The user authenticated with AUTH PLAIN, if any
+	Data     string // The message with headers, as sent after DATA
+}
+
+// A minimal SMTP server on the loopback interface recording the emails it receives. It accepts AUTH PLAIN with any password and does not support STARTTLS
+type SMTPServer struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+// Creates and starts an SMTP server. The caller should call Close when done
+func NewSMTPServer() (*SMTPServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &SMTPServer{listener: listener}
+	go s.serve()
+	return s, nil
+}
+
+// Returns the address of the server (eg. "127.0.0.1:2525")
+func (s *SMTPServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Stops the server
+func (s *SMTPServer) Close() error {
+	return s.listener.Close()
+}
+
+// Returns the messages received so far
+func (s *SMTPServer) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+func (s *SMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *SMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...any) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+
+	var msg Message
+	reply("220 notifytest ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			reply("250-notifytest")
+			reply("250 AUTH PLAIN")
+		case "HELO", "NOOP":
+			reply("250 OK")
+		case "AUTH":
+			mechanism, initial, _ := strings.Cut(arg, " ")
+			if !strings.EqualFold(mechanism, "PLAIN") {
+				reply("504 Unrecognized authentication type")
+				continue
+			}
+			decoded, err := base64.StdEncoding.DecodeString(initial)
+			parts := strings.Split(string(decoded), "\x00")
+			if err != nil || len(parts) != 3 {
+				reply("501 Invalid AUTH PLAIN response")
+				continue
+			}
+			msg.Username = parts[1]
+			reply("235 Authentication successful")
+		case "MAIL":
+			msg.From = address(arg)
+			reply("250 OK")
+		case "RCPT":
+			msg.To = append(msg.To, address(arg))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			msg.Data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			msg = Message{Username: msg.Username}
+			reply("250 OK")
+		case "RSET":
+			msg = Message{Username: msg.Username}
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// Returns the address of a MAIL FROM or RCPT TO argument (eg. "FROM:<a@b.dk>")
+func address(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr = strings.TrimSpace(addr)
+	if i := strings.Index(addr, ">"); i >= 0 {
+		addr = addr[:i]
+	}
+	return strings.TrimPrefix(addr, "<")
+}
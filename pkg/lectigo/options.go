@@ -0,0 +1,105 @@
+package lectigo
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+	_ "time/tzdata" // Embeds the timezone database, so the school timezone can be loaded on systems without one
+
+	"github.com/mattismoel/lectigo/util"
+	"google.golang.org/api/option"
+)
+
+// Settings shared by Lectio and GoogleCalendar instances. Set with the With... option functions
+type options struct {
+	clock    util.Clock
+	location *time.Location
+	baseURL  string
+	session  *SessionStore
+	template *EventTemplate
+	logger   *log.Logger
+
+	calendarOptions []option.ClientOption
+}
+
+// Configures a Lectio or GoogleCalendar instance on creation
+type Option func(*options)
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		clock:    util.SystemClock,
+		location: defaultLocation(),
+		baseURL:  DefaultLectioBaseURL,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Sets the clock used for all date logic (eg. which weeks to sync). Defaults to the system clock
+func WithClock(clock util.Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// Sets the timezone of the school. Lectio dates and times are read in this location, and calendar events are created in it. Defaults to Europe/Copenhagen
+func WithLocation(location *time.Location) Option {
+	return func(o *options) {
+		o.location = location
+	}
+}
+
+// Sets the base URL of Lectio, under which each school has its pages (eg. "https://www.lectio.dk/lectio"). Used to point Lectio at a test server
+func WithBaseURL(baseURL string) Option {
+	return func(o *options) {
+		o.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// Makes Lectio reuse the session saved in the store instead of logging in, and save the session after logging in
+func WithSession(store *SessionStore) Option {
+	return func(o *options) {
+		o.session = store
+	}
+}
+
+// Sets the templates of the summary and description of calendar events. Defaults to the module title and a description with the teacher and homework
+func WithEventTemplate(template *EventTemplate) Option {
+	return func(o *options) {
+		o.template = template
+	}
+}
+
+// Sets the logger progress and warnings are written to. Defaults to standard output, prefixed with "lectio" or "google-calendar"
+func WithLogger(logger *log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// Adds options to the Google Calendar API service (eg. option.WithEndpoint to point GoogleCalendar at a test server)
+func WithCalendarOptions(opts ...option.ClientOption) Option {
+	return func(o *options) {
+		o.calendarOptions = append(o.calendarOptions, opts...)
+	}
+}
+
+// Returns the logger of the options, or a new logger with the prefix if none was set
+func (o *options) loggerOr(prefix string) *log.Logger {
+	if o.logger != nil {
+		return o.logger
+	}
+	return log.New(os.Stdout, prefix, log.LstdFlags)
+}
+
+// Returns the location of the default school timezone. The timezone database is embedded, so loading it cannot fail
+func defaultLocation() *time.Location {
+	location, err := time.LoadLocation(util.DefaultTimezone)
+	if err != nil {
+		panic(err)
+	}
+	return location
+}
@@ -0,0 +1,193 @@
+package lectigo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mattismoel/lectigo/util"
+)
+
+// The rows of the Lectio schedule table (table.s2skema)
+const (
+	scheduleDayRow    = 1 // The row of day headers (eg. "Mandag (9/12)")
+	scheduleInfoRow   = 2 // The row containing the day events of each day (eg. excursions)
+	scheduleModuleRow = 3 // The row containing the modules of each day
+)
+
+// Returned when a page does not contain a Lectio schedule table, for example if the session is logged out or Lectio has changed its markup
+var ErrScheduleNotFound = errors.New("Could not find a schedule table in the Lectio page")
+
+// A problem found while parsing a Lectio schedule, which did not stop the rest of the schedule from being parsed
+type ParseWarning struct {
+	Week     util.Week `json:"week"`     // The week of the schedule
+	ModuleID string    `json:"moduleID"` // The ID of the module the warning regards, if any
+	Message  string    `json:"message"`  // Description of the problem
+}
+
+func (w ParseWarning) String() string {
+	if w.ModuleID == "" {
+		return fmt.Sprintf("week %v: %s", w.Week, w.Message)
+	}
+	return fmt.Sprintf("week %v, module %s: %s", w.Week, w.ModuleID, w.Message)
+}
+
+// Parses a Lectio schedule page (SkemaNy.aspx) of the input ISO week. Dates and times are read in the input location.
+// Modules are returned in the order they appear in the page. Problems with single modules are returned as warnings, while an error is returned if the schedule itself cannot be read
+func ParseSchedule(r io.Reader, week util.Week, location *time.Location) ([]Module, []ParseWarning, error) {
+	document, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	modules, _, warnings, err := parseScheduleDocument(document, week, location)
+	return modules, warnings, err
+}
+
+// Parses the modules and day events of a Lectio schedule page
+func parseScheduleDocument(document *goquery.Document, week util.Week, location *time.Location) ([]Module, []DayEvent, []ParseWarning, error) {
+	table := document.Find("table.s2skema>tbody").First()
+	if table.Length() == 0 {
+		return nil, nil, nil, ErrScheduleNotFound
+	}
+
+	var modules []Module
+	var warnings []ParseWarning
+	warn := func(moduleID, format string, a ...any) {
+		warnings = append(warnings, ParseWarning{Week: week, ModuleID: moduleID, Message: fmt.Sprintf(format, a...)})
+	}
+
+	rows := table.Find("tr")
+	if rows.Length() <= scheduleModuleRow {
+		return nil, nil, nil, fmt.Errorf("Expected at least %d rows in the schedule table, got %d", scheduleModuleRow+1, rows.Length())
+	}
+
+	weekStartString := strings.TrimSpace(rows.Eq(scheduleDayRow).Find("td:nth-child(2)").Text())
+	weekStart, err := parseDate(weekStartString, week, location)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("Could not parse date: %v", err)
+	}
+
+	rows.Eq(scheduleModuleRow).Find("td").Each(func(col int, s *goquery.Selection) {
+		// If sidebar column return prematurely
+		if col == 0 {
+			return
+		}
+
+		// Gets the current date of the module
+		date := weekStart.AddDate(0, 0, col-1)
+
+		s.Find("a.s2skemabrik").Each(func(i int, e *goquery.Selection) {
+			module, moduleWarnings := parseModule(e, date)
+			for _, message := range moduleWarnings {
+				warn(module.Id, "%s", message)
+			}
+			if module.Id == "" {
+				return
+			}
+			modules = append(modules, module)
+		})
+	})
+
+	dayEvents := parseDayEvents(rows.Eq(scheduleInfoRow), weekStart)
+	return modules, dayEvents, warnings, nil
+}
+
+// Parses a single module (a.s2skemabrik) of the input date. Problems are returned as warning messages
+func parseModule(e *goquery.Selection, date time.Time) (Module, []string) {
+	var warnings []string
+
+	addInfo, _ := e.Attr("data-additionalinfo")
+	if addInfo == "" {
+		warnings = append(warnings, "module has no additional info")
+	}
+
+	var id, title, teacher, room, homework string
+
+	// Get ID of the module
+	href, _ := e.Attr("href")
+	idUrl, err := url.Parse(href)
+	if err != nil {
+		return Module{}, append(warnings, fmt.Sprintf("could not parse module link %q: %v", href, err))
+	}
+	urlParams := idUrl.Query()
+	if urlParams.Has("absid") {
+		id = urlParams.Get("absid")
+	}
+	if urlParams.Has("aftaleid") {
+		id = urlParams.Get("aftaleid")
+		e.Find("div.s2skemabrikcontent > span").Each(func(i int, e *goquery.Selection) {
+			title = e.Text()
+		})
+	}
+	if id == "" {
+		return Module{}, append(warnings, fmt.Sprintf("module link %q has no ID", href))
+	}
+
+	var status = "uændret"
+	var startDate, endDate time.Time
+
+	// The homework is the remainder of the info, and is not searched for other fields
+	header, homework, hasHomework := strings.Cut(addInfo, "Lektier:")
+	if hasHomework {
+		homework = strings.TrimSpace(homework)
+		homework = strings.TrimSpace(strings.TrimSuffix(homework, "[...]"))
+	}
+
+	for i, line := range strings.Split(header, "\n") {
+		if strings.Contains(line, "Hold: ") && title == "" {
+			_, title, _ = strings.Cut(line, ": ")
+			title = strings.TrimSpace(title)
+			continue
+		}
+		if strings.Contains(line, "Lærer: ") || strings.Contains(line, "Lærere: ") {
+			_, teacher, _ = strings.Cut(line, ": ")
+			teacher = strings.TrimSpace(teacher)
+			continue
+		}
+		if strings.Contains(line, "Lokale: ") || strings.Contains(line, "Lokaler: ") {
+			_, room, _ = strings.Cut(line, ": ")
+			room = strings.TrimSpace(room)
+			continue
+		}
+
+		if i == 0 && (strings.Contains(line, "Ændret!") || strings.Contains(line, "Aflyst!")) {
+			status = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(line), "!"))
+			continue
+		}
+
+		if strings.Contains(line, "-") && startDate.IsZero() && endDate.IsZero() {
+			startDate, endDate, _ = util.ConvertTimestamp(&date, line)
+			continue
+		}
+	}
+
+	if startDate.IsZero() {
+		warnings = append(warnings, "could not find the time of the module")
+	}
+
+	module := Module{
+		Id:           id,
+		Title:        title,
+		StartDate:    startDate,
+		EndDate:      endDate,
+		Room:         room,
+		Teacher:      teacher,
+		Homework:     homework,
+		ModuleStatus: status,
+		activity:     urlParams.Has("absid") && !urlParams.Has("aftaleid"),
+		version:      fingerprint(href + "\n" + addInfo),
+	}
+	return module, warnings
+}
+
+// Returns a short hash of the input, used to tell whether something has changed
+func fingerprint(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
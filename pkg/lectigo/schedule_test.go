@@ -0,0 +1,59 @@
+package lectigo_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/pkg/lectigo/lectiotest"
+	"github.com/mattismoel/lectigo/util"
+)
+
+// The expected result of parsing a saved schedule page
+type scheduleGolden struct {
+	Week      util.Week              `json:"week"`
+	Modules   []lectigo.Module       `json:"modules"`
+	DayEvents []lectigo.DayEvent     `json:"dayEvents,omitempty"`
+	Warnings  []lectigo.ParseWarning `json:"warnings"`
+}
+
+func TestParseSchedule(t *testing.T) {
+	// The goldens are in Europe/Copenhagen, whatever the local timezone of the machine running the test
+	location := copenhagen(t)
+
+	fixtures, err := lectiotest.ScheduleFixtures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("No saved schedule pages")
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.Name, func(t *testing.T) {
+			page, err := fixture.HTML()
+			if err != nil {
+				t.Fatal(err)
+			}
+			modules, warnings, err := lectigo.ParseSchedule(bytes.NewReader(page), fixture.Week, location)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dayEvents, err := lectigo.ParseDayEvents(bytes.NewReader(page), fixture.Week, location)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := scheduleGolden{Week: fixture.Week, Modules: modules, DayEvents: dayEvents, Warnings: warnings}
+			checkGolden(t, golden, "schedule", strings.TrimSuffix(fixture.File, ".html")+".golden.json")
+		})
+	}
+}
+
+func TestParseScheduleNotFound(t *testing.T) {
+	_, _, err := lectigo.ParseSchedule(strings.NewReader("<html><body><p>Log ind</p></body></html>"), util.Week{Year: 2025, Week: 1}, copenhagen(t))
+	if err != lectigo.ErrScheduleNotFound {
+		t.Errorf("Got error %v, want %v", err, lectigo.ErrScheduleNotFound)
+	}
+}
@@ -0,0 +1,221 @@
+package lectigo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattismoel/lectigo/util"
+)
+
+// Returned when a saved Lectio session has expired and no password is given to log in again
+var ErrLoginRequired = errors.New("The Lectio session has expired and no password is given to log in again")
+
+// Stores the cookies of a Lectio session in an encrypted file, so later runs can reuse the session instead of logging in.
+// Lectio sets a long-lived autologin cookie at login, which keeps the session usable for weeks
+type SessionStore struct {
+	Path string // The path of the encrypted session file
+	Key  []byte // The key the session file is encrypted with (util.KeySize bytes)
+}
+
+// The contents of a session file
+type savedSession struct {
+	BaseURL  string        `json:"baseURL"`
+	SchoolID string        `json:"schoolID"`
+	Username string        `json:"username"`
+	Cookies  []savedCookie `json:"cookies"`
+}
+
+type savedCookie struct {
+	URL      string    `json:"url"` // The URL the cookie was set by
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Path     string    `json:"path"`
+	Domain   string    `json:"domain"`
+	Expires  time.Time `json:"expires"` // Zero for cookies lasting the session
+	Secure   bool      `json:"secure"`
+	HttpOnly bool      `json:"httpOnly"`
+}
+
+// Creates a session store saving to the given path, encrypted with the key at keyPath. A key is created if none exists
+func NewSessionStore(path, keyPath string) (*SessionStore, error) {
+	key, err := util.LoadOrCreateKey(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("Could not load session key: %v", err)
+	}
+	return &SessionStore{Path: path, Key: key}, nil
+}
+
+// Reads the saved session. Returns nil if no session has been saved
+func (s *SessionStore) load() (*savedSession, error) {
+	b, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	b, err = util.Decrypt(s.Key, b)
+	if err != nil {
+		return nil, fmt.Errorf("Could not decrypt session file %s: %v", s.Path, err)
+	}
+	session := &savedSession{}
+	if err := json.Unmarshal(b, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *SessionStore) save(session *savedSession) error {
+	b, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	b, err = util.Encrypt(s.Key, b)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(s.Path, b, 0600)
+}
+
+// Deletes the saved session, if any
+func (s *SessionStore) Clear() error {
+	err := os.Remove(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// A cookie jar remembering the cookies it is given, so they can be saved along with their expiry times
+type sessionJar struct {
+	*cookiejar.Jar
+	mu      sync.Mutex
+	cookies map[string]savedCookie // Cookies by domain, path and name
+}
+
+func newSessionJar() (*sessionJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &sessionJar{Jar: jar, cookies: make(map[string]savedCookie)}, nil
+}
+
+func (j *sessionJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.Jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	for _, cookie := range cookies {
+		saved := savedCookie{
+			URL:      (&url.URL{Scheme: u.Scheme, Host: u.Host}).String(),
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Path:     cookie.Path,
+			Domain:   cookie.Domain,
+			Expires:  cookie.Expires,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+		}
+		if cookie.MaxAge > 0 {
+			saved.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		}
+
+		key := strings.Join([]string{u.Hostname(), cookie.Domain, cookie.Path, cookie.Name}, ";")
+		if cookie.MaxAge < 0 || (!saved.Expires.IsZero() && saved.Expires.Before(now)) {
+			delete(j.cookies, key)
+			continue
+		}
+		j.cookies[key] = saved
+	}
+}
+
+// Returns the unexpired cookies of the jar
+func (j *sessionJar) saved() []savedCookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	var cookies []savedCookie
+	for _, cookie := range j.cookies {
+		if !cookie.Expires.IsZero() && cookie.Expires.Before(now) {
+			continue
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies
+}
+
+// Puts saved cookies back into the jar
+func (j *sessionJar) restore(cookies []savedCookie) {
+	for _, cookie := range cookies {
+		u, err := url.Parse(cookie.URL)
+		if err != nil {
+			continue
+		}
+		j.SetCookies(u, []*http.Cookie{{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Path:     cookie.Path,
+			Domain:   cookie.Domain,
+			Expires:  cookie.Expires,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+		}})
+	}
+}
+
+// Attempts to continue the saved session. Reports whether the session is still logged in
+func (l *Lectio) restoreSession() (bool, error) {
+	session, err := l.session.load()
+	if err != nil || session == nil {
+		return false, err
+	}
+	if session.BaseURL != l.BaseURL || session.SchoolID != l.LoginInfo.SchoolID || session.Username != l.LoginInfo.Username {
+		return false, nil
+	}
+
+	l.jar.restore(session.Cookies)
+	return l.loggedIn()
+}
+
+// Checks whether the session is logged in by visiting the front page. Lectio redirects to the login page if not
+func (l *Lectio) loggedIn() (bool, error) {
+	response, err := l.Client.Get(l.pageURL("forside.aspx"))
+	if err != nil {
+		return false, requestError(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return false, statusError(response)
+	}
+	return !strings.Contains(response.Request.URL.Path, "login.aspx"), nil
+}
+
+// Saves the cookies of the session, if the Lectio instance was created with a session store. Should be called when done using Lectio, as Lectio may renew the cookies
+func (l *Lectio) SaveSession() error {
+	if l.session == nil {
+		return nil
+	}
+	return l.session.save(&savedSession{
+		BaseURL:  l.BaseURL,
+		SchoolID: l.LoginInfo.SchoolID,
+		Username: l.LoginInfo.Username,
+		Cookies:  l.jar.saved(),
+	})
+}
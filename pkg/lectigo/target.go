@@ -0,0 +1,68 @@
+package lectigo
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// The kind of a schedule target, named by the query parameter Lectio selects its schedule with
+type ScheduleTargetKind string
+
+const (
+	TargetTeacher ScheduleTargetKind = "laererid" // The schedule of a teacher
+	TargetRoom    ScheduleTargetKind = "lokaleid" // The booking schedule of a room
+	TargetClass   ScheduleTargetKind = "klasseid" // The schedule of a class
+	TargetStudent ScheduleTargetKind = "elevid"   // The schedule of a student
+)
+
+// Whose schedule to get. The zero value is the schedule of the logged in user
+type ScheduleTarget struct {
+	Kind ScheduleTargetKind
+	Id   string // The Lectio ID of the teacher, room, class or student (eg. "9001")
+}
+
+// Reports whether the target is the logged in user
+func (t ScheduleTarget) IsSelf() bool {
+	return t.Kind == ""
+}
+
+// Returns an error if the target does not have a known kind and an ID
+func (t ScheduleTarget) Validate() error {
+	if t.IsSelf() {
+		return nil
+	}
+	switch t.Kind {
+	case TargetTeacher, TargetRoom, TargetClass, TargetStudent:
+	default:
+		return fmt.Errorf("Unknown schedule target %q", t.Kind)
+	}
+	if t.Id == "" {
+		return fmt.Errorf("The schedule target %s needs an ID", t.Kind)
+	}
+	return nil
+}
+
+// Returns the query parameters selecting the schedule of the target, to add to the week parameter (eg. "&laererid=9001")
+func (t ScheduleTarget) query() string {
+	if t.IsSelf() {
+		return ""
+	}
+	return "&" + string(t.Kind) + "=" + url.QueryEscape(t.Id)
+}
+
+// Describes the target (eg. "teacher 9001")
+func (t ScheduleTarget) String() string {
+	switch t.Kind {
+	case "":
+		return "the logged in user"
+	case TargetTeacher:
+		return "teacher " + t.Id
+	case TargetRoom:
+		return "room " + t.Id
+	case TargetClass:
+		return "class " + t.Id
+	case TargetStudent:
+		return "student " + t.Id
+	}
+	return string(t.Kind) + " " + t.Id
+}
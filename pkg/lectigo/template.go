@@ -0,0 +1,63 @@
+package lectigo
+
+import (
+	"strings"
+	"text/template"
+)
+
+// Templates for the summary and description of the Google Calendar events of modules. The templates are executed with the Module.
+// A nil template keeps the default of Module.ToGoogleEvent
+type EventTemplate struct {
+	Summary     *template.Template
+	Description *template.Template
+}
+
+// Parses summary and description templates (eg. "{{.Title}} i {{.Room}}"). Empty strings keep the defaults
+func NewEventTemplate(summary, description string) (*EventTemplate, error) {
+	t := &EventTemplate{}
+	var err error
+	if summary != "" {
+		t.Summary, err = template.New("summary").Parse(summary)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if description != "" {
+		t.Description, err = template.New("description").Parse(description)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Converts a Lectio module to a Google Calendar event with the summary and description of the templates
+func (t *EventTemplate) ToGoogleEvent(m *Module) (*GoogleEvent, error) {
+	event := m.ToGoogleEvent()
+
+	if t.Summary != nil {
+		summary, err := execute(t.Summary, m)
+		if err != nil {
+			return nil, err
+		}
+		event.Summary = summary
+	}
+	if t.Description != nil {
+		description, err := execute(t.Description, m)
+		if err != nil {
+			return nil, err
+		}
+		event.Description = description
+	}
+
+	return event, nil
+}
+
+func execute(t *template.Template, data any) (string, error) {
+	var sb strings.Builder
+	err := t.Execute(&sb, data)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
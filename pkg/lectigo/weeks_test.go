@@ -0,0 +1,85 @@
+package lectigo_test
+
+import (
+	"io"
+	"log"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/pkg/lectigo/lectiotest"
+	"github.com/mattismoel/lectigo/util"
+)
+
+// Returns the timezone the saved Lectio pages and their goldens are in
+func copenhagen(t *testing.T) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(util.DefaultTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location
+}
+
+// Logs in to the fake Lectio server as its student, with the clock and in Europe/Copenhagen
+func newLectio(t *testing.T, server *lectiotest.Server, clock util.Clock) *lectigo.Lectio {
+	t.Helper()
+	l, err := lectigo.NewLectio(
+		&lectigo.LectioLoginInfo{Username: server.Username, Password: server.Password, SchoolID: server.SchoolID},
+		lectigo.WithBaseURL(server.BaseURL()),
+		lectigo.WithClock(clock),
+		lectigo.WithLocation(copenhagen(t)),
+		lectigo.WithLogger(log.New(io.Discard, "", 0)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestGetTargetScheduleWeeks(t *testing.T) {
+	location := copenhagen(t)
+	tests := []struct {
+		name  string
+		now   time.Time
+		weeks []util.Week // The weeks the returned modules should be in
+	}{
+		{"weekday", time.Date(2024, time.December, 11, 12, 0, 0, 0, location), []util.Week{{Year: 2024, Week: 50}, {Year: 2024, Week: 51}}},
+		{"saturday", time.Date(2024, time.December, 14, 12, 0, 0, 0, location), []util.Week{{Year: 2024, Week: 50}, {Year: 2024, Week: 51}}},
+		{"sunday night", time.Date(2024, time.December, 15, 23, 59, 0, 0, location), []util.Week{{Year: 2024, Week: 50}, {Year: 2024, Week: 51}}},
+		// 23:30 UTC on Sunday is already Monday in Copenhagen
+		{"sunday night in UTC", time.Date(2024, time.December, 15, 23, 30, 0, 0, time.UTC), []util.Week{{Year: 2024, Week: 51}, {Year: 2024, Week: 52}}},
+		{"year end", time.Date(2024, time.December, 29, 20, 0, 0, 0, location), []util.Week{{Year: 2024, Week: 52}, {Year: 2025, Week: 1}}},
+		{"new year's eve", time.Date(2024, time.December, 31, 12, 0, 0, 0, location), []util.Week{{Year: 2025, Week: 1}, {Year: 2025, Week: 2}}},
+	}
+
+	server := lectiotest.NewServer()
+	defer server.Close()
+	clock := util.NewFakeClock(time.Time{})
+	l := newLectio(t, server, clock)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock.Set(test.now)
+			modules, _, err := l.GetTargetScheduleWeeks(lectigo.ScheduleTarget{}, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			seen := make(map[util.Week]bool)
+			for _, m := range modules {
+				seen[util.WeekOf(m.StartDate)] = true
+			}
+			var got []util.Week
+			for week := range seen {
+				got = append(got, week)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i].String() < got[j].String() })
+			if !reflect.DeepEqual(got, test.weeks) {
+				t.Errorf("Got modules of weeks %v, want %v", got, test.weeks)
+			}
+		})
+	}
+}
@@ -3,13 +3,12 @@ package util
 import (
 	"context"
 	"encoding/json"
-	"fmt"
-	"log"
 	"net/http"
 	"os"
-	"time"
+	"path/filepath"
 
 	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
 )
 
 // Rerturns a Lectio status based on the color id of a Google Calendar event
@@ -37,47 +36,26 @@ func ColorIDFromStatus(status string) string {
 	return ""
 }
 
-// Returns the HTTP client from a token.json file, if present
+// Returns the HTTP client from a token.json file, as created by "lectigo auth". Does not authorise through the browser,
+// so commands run by cron or systemd fail instead of waiting for a browser that never comes.
+// The token is refreshed when it expires, and the renewed token is saved to the file
 func GetClient(config *oauth2.Config, tokenPath string) (*http.Client, error) {
+	ctx := context.Background()
 	token, err := tokenFromFile(tokenPath)
 	if err != nil {
-		token, err = getTokenFromWeb(config)
-		if err != nil {
-			return nil, err
-		}
-		saveToken(tokenPath, token)
-	}
-	return config.Client(context.Background(), token), nil
-}
-
-func getTokenFromWeb(config *oauth2.Config) (*oauth2.Token, error) {
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-
-	var authCode string
-
-	fmt.Printf("Visit here: %q\n", authURL)
-
-	server := &http.Server{Addr: ":8080"}
-	http.HandleFunc("/oauth", func(w http.ResponseWriter, r *http.Request) {
-		authCode = r.URL.Query().Get("code")
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := server.Shutdown(ctx); err != nil {
-			log.Fatalf("could not shut down server: %v\n", err)
-		}
-
-	})
-
-	if err := server.ListenAndServe(); err != http.ErrServerClosed {
 		return nil, err
 	}
+	return oauth2.NewClient(ctx, SavingTokenSource(ctx, config, token, tokenPath)), nil
+}
 
-	token, err := config.Exchange(context.TODO(), authCode)
+// Returns an HTTP client authorised as a Google service account. If subject is given, the service account acts as that user through domain-wide delegation
+func GetServiceAccountClient(keyJSON []byte, subject string, scopes ...string) (*http.Client, error) {
+	config, err := google.JWTConfigFromJSON(keyJSON, scopes...)
 	if err != nil {
 		return nil, err
 	}
-
-	return token, nil
+	config.Subject = subject
+	return config.Client(context.Background()), nil
 }
 
 func tokenFromFile(file string) (*oauth2.Token, error) {
@@ -92,8 +70,11 @@ func tokenFromFile(file string) (*oauth2.Token, error) {
 	return token, err
 }
 
-func saveToken(path string, token *oauth2.Token) error {
-	fmt.Printf("Saving credential file to: %s\n", path)
+// Saves the token to a JSON file, creating its directory if needed
+func SaveToken(path string, token *oauth2.Token) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
 	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return err
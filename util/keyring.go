@@ -0,0 +1,168 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/zalando/go-keyring"
+)
+
+// The service name secrets of lectigo are stored under
+const KeyringService = "lectigo"
+
+// Returned when a keyring holds no secret for the requested user
+var ErrSecretNotFound = errors.New("Secret not found in keyring")
+
+// Stores secrets (eg. Lectio passwords) by service and user
+type Keyring interface {
+	Get(service, user string) (string, error) // Returns ErrSecretNotFound if there is no secret
+	Set(service, user, secret string) error
+	Delete(service, user string) error
+}
+
+// The keyring of the operating system (Secret Service on Linux, Keychain on macOS and Credential Manager on Windows)
+type SystemKeyring struct{}
+
+func (SystemKeyring) Get(service, user string) (string, error) {
+	secret, err := keyring.Get(service, user)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrSecretNotFound
+	}
+	return secret, err
+}
+
+func (SystemKeyring) Set(service, user, secret string) error {
+	return keyring.Set(service, user, secret)
+}
+
+func (SystemKeyring) Delete(service, user string) error {
+	err := keyring.Delete(service, user)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return ErrSecretNotFound
+	}
+	return err
+}
+
+// A keyring kept in a file encrypted with a key file. Used where no system keyring is available, for example on servers without a desktop session
+type FileKeyring struct {
+	Path    string // The path of the encrypted keyring file
+	KeyPath string // The path of the key the file is encrypted with. The key is created when the first secret is stored
+
+	mu  sync.Mutex
+	key []byte
+}
+
+// Creates a file keyring at the given path, encrypted with the key at keyPath. Neither file is created until a secret is stored
+func NewFileKeyring(path, keyPath string) *FileKeyring {
+	return &FileKeyring{Path: path, KeyPath: keyPath}
+}
+
+func (k *FileKeyring) Get(service, user string) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	secrets, err := k.read()
+	if err != nil {
+		return "", err
+	}
+	secret, ok := secrets[service][user]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return secret, nil
+}
+
+func (k *FileKeyring) Set(service, user, secret string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	secrets, err := k.read()
+	if err != nil {
+		return err
+	}
+	if secrets[service] == nil {
+		secrets[service] = make(map[string]string)
+	}
+	secrets[service][user] = secret
+	return k.write(secrets)
+}
+
+func (k *FileKeyring) Delete(service, user string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	secrets, err := k.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[service][user]; !ok {
+		return ErrSecretNotFound
+	}
+	delete(secrets[service], user)
+	return k.write(secrets)
+}
+
+// Reads the secrets of the file by service and user. A missing file holds no secrets
+func (k *FileKeyring) read() (map[string]map[string]string, error) {
+	secrets := make(map[string]map[string]string)
+
+	b, err := os.ReadFile(k.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := k.loadKey(false)
+	if err != nil {
+		return nil, err
+	}
+	b, err = Decrypt(key, b)
+	if err != nil {
+		return nil, fmt.Errorf("Could not decrypt keyring file %s: %v", k.Path, err)
+	}
+	if err := json.Unmarshal(b, &secrets); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+func (k *FileKeyring) write(secrets map[string]map[string]string) error {
+	b, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	key, err := k.loadKey(true)
+	if err != nil {
+		return err
+	}
+	b, err = Encrypt(key, b)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(k.Path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(k.Path, b, 0600)
+}
+
+// Returns the key of the keyring, creating it if create is set and there is none. Must be called with the lock held
+func (k *FileKeyring) loadKey(create bool) ([]byte, error) {
+	if k.key != nil {
+		return k.key, nil
+	}
+	load := LoadKey
+	if create {
+		load = LoadOrCreateKey
+	}
+	key, err := load(k.KeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("Could not load keyring key: %v", err)
+	}
+	k.key = key
+	return key, nil
+}
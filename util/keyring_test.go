@@ -0,0 +1,91 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestFileKeyringCreatesKeyOnSet(t *testing.T) {
+	dir := t.TempDir()
+	path, keyPath := filepath.Join(dir, "keyring"), filepath.Join(dir, "keyring.key")
+
+	k := NewFileKeyring(path, keyPath)
+	if _, err := k.Get(KeyringService, "133/elev"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Get from an empty keyring returned %v, want %v", err, ErrSecretNotFound)
+	}
+	if err := k.Delete(KeyringService, "133/elev"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Delete from an empty keyring returned %v, want %v", err, ErrSecretNotFound)
+	}
+	for _, p := range []string{path, keyPath} {
+		if _, err := os.Stat(p); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s exists before a secret is stored", filepath.Base(p))
+		}
+	}
+
+	if err := k.Set(KeyringService, "133/elev", "hemmelig"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(keyPath)
+	if err != nil {
+		t.Fatalf("No key created when storing a secret: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Key has permissions %v, want 0600", info.Mode().Perm())
+	}
+
+	// Another keyring reads the secret with the same key
+	secret, err := NewFileKeyring(path, keyPath).Get(KeyringService, "133/elev")
+	if err != nil || secret != "hemmelig" {
+		t.Errorf("Get = %q, %v, want hemmelig", secret, err)
+	}
+}
+
+func TestFileKeyringMissingKey(t *testing.T) {
+	dir := t.TempDir()
+	path, keyPath := filepath.Join(dir, "keyring"), filepath.Join(dir, "keyring.key")
+	if err := NewFileKeyring(path, keyPath).Set(KeyringService, "133/elev", "hemmelig"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(keyPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// The secrets cannot be read without their key, and a new key must not be made in its place
+	if _, err := NewFileKeyring(path, keyPath).Get(KeyringService, "133/elev"); err == nil || errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Get without the key returned %v, want an error loading the key", err)
+	}
+	if _, err := os.Stat(keyPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Reading the keyring created a new key")
+	}
+}
+
+// Roster users are synced concurrently with one shared keyring
+func TestFileKeyringConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	k := NewFileKeyring(filepath.Join(dir, "keyring"), filepath.Join(dir, "keyring.key"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(user string) {
+			defer wg.Done()
+			k.Get(KeyringService, user)
+			if err := k.Set(KeyringService, user, "hemmelig"+user); err != nil {
+				t.Error(err)
+			}
+		}(fmt.Sprintf("133/elev%d", i))
+	}
+	wg.Wait()
+
+	reopened := NewFileKeyring(k.Path, k.KeyPath)
+	for i := 0; i < 10; i++ {
+		user := fmt.Sprintf("133/elev%d", i)
+		if secret, err := reopened.Get(KeyringService, user); err != nil || secret != "hemmelig"+user {
+			t.Errorf("Get(%s) = %q, %v, want hemmelig%s", user, secret, err, user)
+		}
+	}
+}
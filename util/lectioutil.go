@@ -6,26 +6,12 @@ import (
 	"time"
 )
 
-func ConvertTimestamp(date *time.Time, stamp string) (time.Time, time.Duration, error) {
-	// location, err := time.LoadLocation("Europe/Copenhagen")
-	// if err != nil {
-	// 	return startTime, endTime, err
-	// }
-	// layout := "15:04"
-	// split := strings.Split(s, " til ")
-	// if len(split) != 2 {
-	// 	return startTime, endTime, err
-	// }
-	//
-	// startTime, err = time.ParseInLocation(layout, split[0], location)
-	// if err != nil {
-	// 	return startTime, endTime, err
-	// }
-	//
-	// date := startTime.Format("2/1-2006")
-	// endTime, err = time.ParseInLocation(layout, date+" "+split[1], location)
-	// if err != nil {
-	// 	return startTime, endTime, err
+// The timezone of Lectio schools, used unless another one is configured
+const DefaultTimezone = "Europe/Copenhagen"
+
+// Converts a Lectio timestamp (eg. "09:55 - 11:25") on the input date to a start and end time.
+// The times are wall clock times in the location of the input date, so the result is correct across DST changes
+func ConvertTimestamp(date *time.Time, stamp string) (start time.Time, end time.Time, err error) {
 	timePattern := `(\d{2}:\d{2}) - (\d{2}:\d{2})`
 
 	// Find the time matches in the input string
@@ -33,28 +19,21 @@ func ConvertTimestamp(date *time.Time, stamp string) (time.Time, time.Duration,
 	matches := re.FindStringSubmatch(stamp)
 
 	if len(matches) != 3 {
-		return time.Time{}, 0, fmt.Errorf("Invalid input string format")
+		return time.Time{}, time.Time{}, fmt.Errorf("Invalid input string format")
 	}
 
 	// Parse the start time and end time into time.Time objects
 	sTime, err := time.Parse("15:04", matches[1])
 	if err != nil {
-		return time.Time{}, 0, err
+		return time.Time{}, time.Time{}, err
 	}
 
 	eTime, err := time.Parse("15:04", matches[2])
 	if err != nil {
-		return time.Time{}, 0, err
-	}
-
-	duration := eTime.Sub(sTime)
-
-	location, err := time.LoadLocation("Europe/Copenhagen")
-	if err != nil {
-		return time.Time{}, 0, err
+		return time.Time{}, time.Time{}, err
 	}
 
-	return time.Date(date.Year(), date.Month(), date.Day(), sTime.Hour(), sTime.Minute(), 0, 0, location), duration, nil
-
-	// return startTime, endTime, nil
+	start = time.Date(date.Year(), date.Month(), date.Day(), sTime.Hour(), sTime.Minute(), 0, 0, date.Location())
+	end = time.Date(date.Year(), date.Month(), date.Day(), eTime.Hour(), eTime.Minute(), 0, 0, date.Location())
+	return start, end, nil
 }
@@ -0,0 +1,81 @@
+package util
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestConvertTimestamp(t *testing.T) {
+	copenhagen, err := time.LoadLocation(DefaultTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		date      time.Time
+		stamp     string
+		wantStart string
+		wantEnd   string
+		wantHours float64 // The elapsed time between start and end
+	}{
+		{
+			name: "winter", date: time.Date(2025, time.January, 13, 0, 0, 0, 0, copenhagen), stamp: "09:55 - 11:25",
+			wantStart: "2025-01-13T09:55:00+01:00", wantEnd: "2025-01-13T11:25:00+01:00", wantHours: 1.5,
+		},
+		{
+			name: "summer", date: time.Date(2025, time.June, 2, 0, 0, 0, 0, copenhagen), stamp: "09:55 - 11:25",
+			wantStart: "2025-06-02T09:55:00+02:00", wantEnd: "2025-06-02T11:25:00+02:00", wantHours: 1.5,
+		},
+		{
+			name: "last sunday of march", date: time.Date(2025, time.March, 30, 0, 0, 0, 0, copenhagen), stamp: "08:15 - 09:45",
+			wantStart: "2025-03-30T08:15:00+02:00", wantEnd: "2025-03-30T09:45:00+02:00", wantHours: 1.5,
+		},
+		{
+			name: "monday after the last sunday of march", date: time.Date(2025, time.March, 31, 0, 0, 0, 0, copenhagen), stamp: "08:15 - 09:45",
+			wantStart: "2025-03-31T08:15:00+02:00", wantEnd: "2025-03-31T09:45:00+02:00", wantHours: 1.5,
+		},
+		{
+			name: "across the change in march", date: time.Date(2025, time.March, 30, 0, 0, 0, 0, copenhagen), stamp: "01:00 - 04:00",
+			wantStart: "2025-03-30T01:00:00+01:00", wantEnd: "2025-03-30T04:00:00+02:00", wantHours: 2,
+		},
+		{
+			name: "last sunday of october", date: time.Date(2025, time.October, 26, 0, 0, 0, 0, copenhagen), stamp: "08:15 - 09:45",
+			wantStart: "2025-10-26T08:15:00+01:00", wantEnd: "2025-10-26T09:45:00+01:00", wantHours: 1.5,
+		},
+		{
+			name: "friday before the last sunday of october", date: time.Date(2025, time.October, 24, 0, 0, 0, 0, copenhagen), stamp: "08:15 - 09:45",
+			wantStart: "2025-10-24T08:15:00+02:00", wantEnd: "2025-10-24T09:45:00+02:00", wantHours: 1.5,
+		},
+		{
+			name: "across the change in october", date: time.Date(2025, time.October, 26, 0, 0, 0, 0, copenhagen), stamp: "01:00 - 04:00",
+			wantStart: "2025-10-26T01:00:00+02:00", wantEnd: "2025-10-26T04:00:00+01:00", wantHours: 4,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start, end, err := ConvertTimestamp(&test.date, test.stamp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := start.Format(time.RFC3339); got != test.wantStart {
+				t.Errorf("Start = %s, want %s", got, test.wantStart)
+			}
+			if got := end.Format(time.RFC3339); got != test.wantEnd {
+				t.Errorf("End = %s, want %s", got, test.wantEnd)
+			}
+			if got := end.Sub(start).Hours(); got != test.wantHours {
+				t.Errorf("Duration = %vh, want %vh", got, test.wantHours)
+			}
+		})
+	}
+}
+
+func TestConvertTimestampInvalid(t *testing.T) {
+	date := time.Date(2025, time.January, 13, 0, 0, 0, 0, time.UTC)
+	for _, stamp := range []string{"", "09:55", "9:55 - 11:25", "Hele dagen"} {
+		if _, _, err := ConvertTimestamp(&date, stamp); err == nil {
+			t.Errorf("ConvertTimestamp(%q) did not return an error", stamp)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package util
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// The redirect URL used by the manual flow. Nothing listens on it, so the browser shows an error page whose address holds the code
+const ManualRedirectURL = "http://127.0.0.1"
+
+// Returned when the OAuth state sent back by the browser is not the one of the flow, as the response may come from another site
+var ErrStateMismatch = errors.New("The OAuth state returned does not match, the authorisation was not started by lectigo")
+
+// The state and PKCE verifier of a single authorisation
+type authorization struct {
+	state    string
+	verifier string
+}
+
+func newAuthorization() (*authorization, error) {
+	state, err := randomToken(24)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := randomToken(48)
+	if err != nil {
+		return nil, err
+	}
+	return &authorization{state: state, verifier: verifier}, nil
+}
+
+// Returns the URL the user visits to authorise lectigo, with the state and PKCE challenge (S256) of the authorisation
+func (a *authorization) authCodeURL(config *oauth2.Config) string {
+	challenge := sha256.Sum256([]byte(a.verifier))
+	return config.AuthCodeURL(a.state, oauth2.AccessTypeOffline,
+		oauth2.SetAuthURLParam("prompt", "consent"),
+		oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:])),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+}
+
+// Exchanges the authorisation code for a token, proving the authorisation was started here with the PKCE verifier
+func (a *authorization) exchange(ctx context.Context, config *oauth2.Config, code string) (*oauth2.Token, error) {
+	token, err := config.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", a.verifier))
+	if err != nil {
+		return nil, fmt.Errorf("Could not exchange the authorisation code for a token: %v", err)
+	}
+	return token, nil
+}
+
+// Reads the code from the query of an OAuth redirect, checking the state and any error Google returned
+func (a *authorization) code(query url.Values) (string, error) {
+	if e := query.Get("error"); e != "" {
+		return "", fmt.Errorf("The authorisation was not granted: %s", e)
+	}
+	if query.Get("state") != a.state {
+		return "", ErrStateMismatch
+	}
+	code := query.Get("code")
+	if code == "" {
+		return "", errors.New("No authorisation code was returned")
+	}
+	return code, nil
+}
+
+// Authorises lectigo with a server listening on a random port of the loopback interface, which Google redirects the browser to.
+// The URL to visit is passed to the visit function. Blocks until the browser is redirected back or the context is done
+func AuthorizeLoopback(ctx context.Context, config *oauth2.Config, visit func(authURL string)) (*oauth2.Token, error) {
+	auth, err := newAuthorization()
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("Could not listen for the OAuth redirect: %v", err)
+	}
+
+	loopbackConfig := *config
+	loopbackConfig.RedirectURL = fmt.Sprintf("http://%s/oauth", listener.Addr().String())
+
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+	var once sync.Once
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth", func(w http.ResponseWriter, r *http.Request) {
+		code, err := auth.code(r.URL.Query())
+		if errors.Is(err, ErrStateMismatch) {
+			// Not the response of this flow. Keeps waiting for the right one
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(w, "<p>%s</p>", html.EscapeString(err.Error()))
+		} else {
+			fmt.Fprint(w, "<p>lectigo is now authorised. You can close this window.</p>")
+		}
+		once.Do(func() { results <- result{code, err} })
+	})
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Close()
+
+	visit(auth.authCodeURL(&loopbackConfig))
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-results:
+		if res.err != nil {
+			return nil, res.err
+		}
+		return auth.exchange(ctx, &loopbackConfig, res.code)
+	}
+}
+
+// Authorises lectigo without a server, for machines the browser cannot reach (eg. over SSH).
+// The URL to visit is written to out, and the user pastes the address the browser was redirected to (or just the code) into in
+func AuthorizeManual(ctx context.Context, config *oauth2.Config, in io.Reader, out io.Writer) (*oauth2.Token, error) {
+	auth, err := newAuthorization()
+	if err != nil {
+		return nil, err
+	}
+
+	manualConfig := *config
+	manualConfig.RedirectURL = ManualRedirectURL
+
+	fmt.Fprintf(out, "Visit the following URL in a browser on any device and allow lectigo access:\n\n%s\n\n", auth.authCodeURL(&manualConfig))
+	fmt.Fprintln(out, "The browser is then sent to a page that cannot be reached. Copy the address of that page and paste it here:")
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return nil, fmt.Errorf("Could not read the redirect address: %v", err)
+	}
+	line = strings.TrimSpace(line)
+
+	code := line
+	if redirect, err := url.Parse(line); err == nil && redirect.RawQuery != "" {
+		code, err = auth.code(redirect.Query())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if code == "" {
+		return nil, errors.New("No authorisation code was given")
+	}
+	return auth.exchange(ctx, &manualConfig, code)
+}
+
+// A token source saving the token to a file whenever it is refreshed
+type savingTokenSource struct {
+	source oauth2.TokenSource
+	path   string
+
+	mu    sync.Mutex
+	saved string // The access token last saved
+}
+
+func (s *savingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.source.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token.AccessToken != s.saved {
+		if err := SaveToken(s.path, token); err != nil {
+			return nil, fmt.Errorf("Could not save refreshed token: %v", err)
+		}
+		s.saved = token.AccessToken
+	}
+	return token, nil
+}
+
+// Returns a token source, which refreshes the token when it expires and saves the renewed token to the path
+func SavingTokenSource(ctx context.Context, config *oauth2.Config, token *oauth2.Token, path string) oauth2.TokenSource {
+	return oauth2.ReuseTokenSource(token, &savingTokenSource{
+		source: config.TokenSource(ctx, token),
+		path:   path,
+		saved:  token.AccessToken,
+	})
+}
+
+// Returns a random URL-safe string of n random bytes
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,120 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A daily period of time (eg. "22:00-06:00"), given as offsets from midnight. A period ending before it starts spans midnight
+type QuietPeriod struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// Parses a period of the form "HH:MM-HH:MM"
+func ParseQuietPeriod(s string) (QuietPeriod, error) {
+	startString, endString, ok := strings.Cut(s, "-")
+	if !ok {
+		return QuietPeriod{}, fmt.Errorf("Invalid period %q. Use the form 22:00-06:00", s)
+	}
+	start, err := parseTimeOfDay(startString)
+	if err != nil {
+		return QuietPeriod{}, fmt.Errorf("Invalid period %q: %v", s, err)
+	}
+	end, err := parseTimeOfDay(endString)
+	if err != nil {
+		return QuietPeriod{}, fmt.Errorf("Invalid period %q: %v", s, err)
+	}
+	return QuietPeriod{Start: start, End: end}, nil
+}
+
+// Parses a time of day of the form "HH:MM" to the offset from midnight
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of the form 15:04", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Reports whether the wall clock time of t is within the period. On days with a DST change, the time since midnight differs from the wall clock time, so the clock is read instead
+func (p QuietPeriod) Contains(t time.Time) bool {
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if p.Start <= p.End {
+		return offset >= p.Start && offset < p.End
+	}
+	return offset >= p.Start || offset < p.End
+}
+
+func (p QuietPeriod) String() string {
+	format := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return format(p.Start) + "-" + format(p.End)
+}
+
+// Times in which nothing should be done, such as nights and weekends
+type QuietHours struct {
+	Periods []QuietPeriod  // Daily quiet periods
+	Days    []time.Weekday // Days that are quiet all day
+}
+
+// Parses quiet periods of the form "22:00-06:00" and weekdays (eg. "sat" or "lørdag")
+func ParseQuietHours(periods []string, days []string) (*QuietHours, error) {
+	q := &QuietHours{}
+	for _, s := range periods {
+		period, err := ParseQuietPeriod(s)
+		if err != nil {
+			return nil, err
+		}
+		q.Periods = append(q.Periods, period)
+	}
+	for _, s := range days {
+		day, err := ParseWeekday(s)
+		if err != nil {
+			return nil, err
+		}
+		q.Days = append(q.Days, day)
+	}
+	return q, nil
+}
+
+// Reports whether t is within the quiet hours. t should be in the location the quiet hours are meant in
+func (q *QuietHours) Contains(t time.Time) bool {
+	for _, day := range q.Days {
+		if t.Weekday() == day {
+			return true
+		}
+	}
+	for _, period := range q.Periods {
+		if period.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// The English and Danish names of the weekdays
+var weekdayNames = map[string]time.Weekday{
+	"monday": time.Monday, "mandag": time.Monday,
+	"tuesday": time.Tuesday, "tirsdag": time.Tuesday,
+	"wednesday": time.Wednesday, "onsdag": time.Wednesday,
+	"thursday": time.Thursday, "torsdag": time.Thursday,
+	"friday": time.Friday, "fredag": time.Friday,
+	"saturday": time.Saturday, "lørdag": time.Saturday,
+	"sunday": time.Sunday, "søndag": time.Sunday,
+}
+
+// Parses an English or Danish weekday name. Names may be shortened to their first three letters (eg. "sat" or "lør")
+func ParseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len([]rune(s)) >= 3 {
+		for name, day := range weekdayNames {
+			if strings.HasPrefix(name, s) {
+				return day, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("Unknown weekday %q", s)
+}
@@ -0,0 +1,83 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietPeriodContains(t *testing.T) {
+	copenhagen, err := time.LoadLocation(DefaultTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	night, err := ParseQuietPeriod("22:00-06:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	morning, err := ParseQuietPeriod("03:00-08:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		period QuietPeriod
+		t      time.Time
+		want   bool
+	}{
+		{"evening", night, time.Date(2025, time.January, 13, 21, 59, 0, 0, copenhagen), false},
+		{"start", night, time.Date(2025, time.January, 13, 22, 0, 0, 0, copenhagen), true},
+		{"midnight", night, time.Date(2025, time.January, 14, 0, 0, 0, 0, copenhagen), true},
+		{"end", night, time.Date(2025, time.January, 14, 6, 0, 0, 0, copenhagen), false},
+		{"within", morning, time.Date(2025, time.January, 14, 7, 59, 0, 0, copenhagen), true},
+		{"before", morning, time.Date(2025, time.January, 14, 2, 59, 0, 0, copenhagen), false},
+
+		// On the last Sunday of March the clock skips from 02:00 to 03:00, so 06:30 is only 5.5 hours after midnight
+		{"after the end in march", night, time.Date(2025, time.March, 30, 6, 30, 0, 0, copenhagen), false},
+		{"before the end in march", night, time.Date(2025, time.March, 30, 5, 30, 0, 0, copenhagen), true},
+		{"after the start in march", morning, time.Date(2025, time.March, 30, 3, 30, 0, 0, copenhagen), true},
+		{"before the end of the day in march", night, time.Date(2025, time.March, 30, 21, 30, 0, 0, copenhagen), false},
+		// On the last Sunday of October the clock turns back from 03:00 to 02:00, so 05:30 is 6.5 hours after midnight
+		{"before the end in october", night, time.Date(2025, time.October, 26, 5, 30, 0, 0, copenhagen), true},
+		{"after the end in october", night, time.Date(2025, time.October, 26, 6, 30, 0, 0, copenhagen), false},
+		{"before the start in october", morning, time.Date(2025, time.October, 26, 2, 30, 0, 0, copenhagen), false},
+		{"before the start of the night in october", night, time.Date(2025, time.October, 26, 21, 30, 0, 0, copenhagen), false},
+		{"after the start of the night in october", night, time.Date(2025, time.October, 26, 22, 30, 0, 0, copenhagen), true},
+	}
+	for _, test := range tests {
+		if got := test.period.Contains(test.t); got != test.want {
+			t.Errorf("%s: %v.Contains(%v) = %v, want %v", test.name, test.period, test.t, got, test.want)
+		}
+	}
+}
+
+func TestQuietHoursContains(t *testing.T) {
+	q, err := ParseQuietHours([]string{"22:00-06:00"}, []string{"lør", "sunday"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		t    time.Time
+		want bool
+	}{
+		{time.Date(2025, time.January, 17, 12, 0, 0, 0, time.UTC), false}, // Friday
+		{time.Date(2025, time.January, 17, 23, 0, 0, 0, time.UTC), true},
+		{time.Date(2025, time.January, 18, 12, 0, 0, 0, time.UTC), true}, // Saturday
+		{time.Date(2025, time.January, 19, 12, 0, 0, 0, time.UTC), true}, // Sunday
+		{time.Date(2025, time.January, 20, 12, 0, 0, 0, time.UTC), false},
+	}
+	for _, test := range tests {
+		if got := q.Contains(test.t); got != test.want {
+			t.Errorf("Contains(%v) = %v, want %v", test.t, got, test.want)
+		}
+	}
+}
+
+func TestParseQuietHoursInvalid(t *testing.T) {
+	if _, err := ParseQuietHours([]string{"22-06"}, nil); err == nil {
+		t.Errorf("Parsed the period 22-06")
+	}
+	if _, err := ParseQuietHours(nil, []string{"fr"}); err == nil {
+		t.Errorf("Parsed the weekday fr, which is too short to tell apart")
+	}
+}
@@ -1,23 +1,52 @@
 package util
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+// An ISO 8601 week, identified by its week-numbering year and week number.
+// Near New Year the ISO year may differ from the calendar year of some of its days (eg. 31/12/2024 is in week 1 of 2025)
+type Week struct {
+	Year int `json:"year"` // The ISO week-numbering year
+	Week int `json:"week"` // The ISO week number (1-53)
+}
+
+// Returns the ISO week containing the input time
+func WeekOf(t time.Time) Week {
+	year, week := t.ISOWeek()
+	return Week{Year: year, Week: week}
+}
+
+// Returns the monday of the week at midnight in the given location
+func (w Week) Monday(location *time.Location) time.Time {
+	// January 4th is always in the first ISO week of its year
+	jan4 := time.Date(w.Year, time.January, 4, 0, 0, 0, 0, location)
+	offset := (int(jan4.Weekday()) + 6) % 7
+	return time.Date(w.Year, time.January, 4-offset+7*(w.Week-1), 0, 0, 0, 0, location)
+}
+
+// Returns the week n weeks after w. Wraps around to the next (or previous) ISO year when needed
+func (w Week) Add(n int) Week {
+	return WeekOf(w.Monday(time.UTC).AddDate(0, 0, 7*n))
+}
+
+// Returns the week in the format of Lectio's week query parameter (eg. "012025" for week 1 of 2025)
+func (w Week) LectioString() string {
+	return fmt.Sprintf("%02d%d", w.Week, w.Year)
+}
+
+// Returns the week in ISO 8601 notation (eg. "2025-W01")
+func (w Week) String() string {
+	return fmt.Sprintf("%d-W%02d", w.Year, w.Week)
+}
 
 // Returns the input date at midnight
 func RoundDateToDay(t time.Time) time.Time {
 	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
 }
 
-// Gets the date of the monday of the current week.
-func GetMonday() (time.Time, error) {
-	location, err := time.LoadLocation("Europe/Copenhagen")
-	if err != nil {
-		return time.Time{}, err
-	}
-	t := time.Now()
-	off := int(t.Weekday()) - int(time.Monday)
-	if off < 0 {
-		off += 7 // Adjust if today is Sunday or earlier in the week
-	}
-	return time.Date(t.Year(), t.Month(), t.Day()-off, 0, 0, 0, 0, location), nil
+// Gets the date of the monday of the current week in the given location, according to the input clock.
+func GetMonday(clock Clock, location *time.Location) time.Time {
+	return WeekOf(clock.Now().In(location)).Monday(location)
 }
-
@@ -0,0 +1,117 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWeekMonday(t *testing.T) {
+	tests := []struct {
+		week Week
+		want string
+	}{
+		{Week{2024, 50}, "2024-12-09"},
+		{Week{2024, 52}, "2024-12-23"},
+		{Week{2025, 1}, "2024-12-30"},
+		{Week{2020, 53}, "2020-12-28"},
+		{Week{2021, 1}, "2021-01-04"},
+		{Week{2026, 1}, "2025-12-29"},
+	}
+	for _, test := range tests {
+		got := test.week.Monday(time.UTC).Format("2006-01-02")
+		if got != test.want {
+			t.Errorf("%v.Monday() = %s, want %s", test.week, got, test.want)
+		}
+	}
+}
+
+func TestWeekAdd(t *testing.T) {
+	tests := []struct {
+		week Week
+		n    int
+		want Week
+	}{
+		{Week{2024, 50}, 1, Week{2024, 51}},
+		{Week{2024, 52}, 1, Week{2025, 1}},
+		{Week{2024, 52}, 3, Week{2025, 3}},
+		{Week{2025, 1}, -1, Week{2024, 52}},
+		{Week{2020, 52}, 1, Week{2020, 53}},
+		{Week{2020, 53}, 1, Week{2021, 1}},
+		{Week{2021, 1}, -1, Week{2020, 53}},
+		{Week{2024, 10}, 0, Week{2024, 10}},
+	}
+	for _, test := range tests {
+		if got := test.week.Add(test.n); got != test.want {
+			t.Errorf("%v.Add(%d) = %v, want %v", test.week, test.n, got, test.want)
+		}
+	}
+}
+
+func TestWeekOf(t *testing.T) {
+	tests := []struct {
+		date string
+		want Week
+	}{
+		{"2024-12-29", Week{2024, 52}},
+		{"2024-12-30", Week{2025, 1}},
+		{"2024-12-31", Week{2025, 1}},
+		{"2021-01-03", Week{2020, 53}},
+		{"2021-01-04", Week{2021, 1}},
+	}
+	for _, test := range tests {
+		date, _ := time.Parse("2006-01-02", test.date)
+		if got := WeekOf(date); got != test.want {
+			t.Errorf("WeekOf(%s) = %v, want %v", test.date, got, test.want)
+		}
+	}
+}
+
+func TestWeekLectioString(t *testing.T) {
+	tests := []struct {
+		week Week
+		want string
+	}{
+		{Week{2025, 1}, "012025"},
+		{Week{2024, 9}, "092024"},
+		{Week{2024, 52}, "522024"},
+		{Week{2020, 53}, "532020"},
+	}
+	for _, test := range tests {
+		if got := test.week.LectioString(); got != test.want {
+			t.Errorf("%v.LectioString() = %q, want %q", test.week, got, test.want)
+		}
+	}
+}
+
+func TestGetMonday(t *testing.T) {
+	copenhagen, err := time.LoadLocation(DefaultTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"weekday", time.Date(2024, time.December, 11, 12, 0, 0, 0, copenhagen), "2024-12-09"},
+		{"sunday night", time.Date(2024, time.December, 15, 23, 59, 0, 0, copenhagen), "2024-12-09"},
+		// 23:30 UTC on Sunday is already Monday in Copenhagen
+		{"sunday night in UTC", time.Date(2024, time.December, 15, 23, 30, 0, 0, time.UTC), "2024-12-16"},
+		{"saturday", time.Date(2024, time.December, 14, 12, 0, 0, 0, copenhagen), "2024-12-09"},
+		{"monday midnight", time.Date(2024, time.December, 16, 0, 0, 0, 0, copenhagen), "2024-12-16"},
+		{"new year's eve", time.Date(2024, time.December, 31, 12, 0, 0, 0, copenhagen), "2024-12-30"},
+		{"new year's day", time.Date(2025, time.January, 1, 0, 30, 0, 0, copenhagen), "2024-12-30"},
+		{"week 53", time.Date(2021, time.January, 3, 12, 0, 0, 0, copenhagen), "2020-12-28"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			monday := GetMonday(NewFakeClock(test.now), copenhagen)
+			if got := monday.Format("2006-01-02 15:04"); got != test.want+" 00:00" {
+				t.Errorf("GetMonday at %v = %s, want %s 00:00", test.now, got, test.want)
+			}
+			if monday.Location() != copenhagen {
+				t.Errorf("GetMonday at %v is in %v, want %v", test.now, monday.Location(), copenhagen)
+			}
+		})
+	}
+}
@@ -1,9 +1,11 @@
 package util
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"strings"
@@ -100,49 +102,74 @@ func ExportSchools(format, outputPath string) error {
 		return err
 	}
 
-	// If file name does not have file extension, add it
-	extension := fmt.Sprintf(".%s", format)
-	if !strings.HasSuffix(outputPath, extension) {
-		outputPath += extension
+	if format == "xml" {
+		type XMLSchools struct {
+			XMLName xml.Name `xml:"schools"`
+			Schools []school `xml:"school"`
+		}
+		return ExportFile(format, outputPath, &XMLSchools{Schools: schools})
 	}
+	return ExportFile(format, outputPath, schools)
+}
 
-	// Open file for writing
-	f, err := os.OpenFile(outputPath, os.O_RDWR|os.O_CREATE, 0755)
-	if err != nil {
-		return err
+// Implemented by values that can be exported as CSV, as the rows of the file
+type CSVMarshaler interface {
+	MarshalCSV() ([][]string, error)
+}
+
+// Returns an error if the value cannot be encoded in the format, as the format is unknown or the value cannot be exported as csv
+func CheckFormat(format string, v any) error {
+	switch format {
+	case "json", "yaml", "xml":
+		return nil
+	case "csv":
+		if _, ok := v.(CSVMarshaler); !ok {
+			return fmt.Errorf("Cannot export %T as csv", v)
+		}
+		return nil
 	}
+	return fmt.Errorf("Unknown format %q", format)
+}
 
-	// Check format and export accordingly
+// Encodes the value to the writer in a format (json, yaml, xml or csv). Only values implementing CSVMarshaler can be encoded as csv.
+// JSON and XML are written without indentation, as lego listSchools has always exported them
+func Encode(w io.Writer, format string, v any) error {
+	if err := CheckFormat(format, v); err != nil {
+		return err
+	}
 	switch format {
 	case "json":
-		defer f.Close()
-		err := json.NewEncoder(f).Encode(schools)
-		if err != nil {
-			return err
-		}
+		return json.NewEncoder(w).Encode(v)
 	case "yaml":
-		defer f.Close()
-		err := yaml.NewEncoder(f).Encode(schools)
-		if err != nil {
-			return err
-		}
-		break
+		return yaml.NewEncoder(w).Encode(v)
 	case "xml":
-		type XMLSchools struct {
-			XMLName xml.Name `xml:"schools"`
-			Schools []school `xml:"school"`
-		}
+		return xml.NewEncoder(w).Encode(v)
+	}
+	records, err := v.(CSVMarshaler).MarshalCSV()
+	if err != nil {
+		return err
+	}
+	return csv.NewWriter(w).WriteAll(records)
+}
 
-		xmlSchools := &XMLSchools{}
-		xmlSchools.Schools = schools
+// Exports the value in a format (see Encode) to a file at the output path. The extension of the format is added to the path if missing.
+// The format is checked before the file is opened, so an existing file is left untouched by a format that cannot be exported
+func ExportFile(format, outputPath string, v any) error {
+	if err := CheckFormat(format, v); err != nil {
+		return err
+	}
 
-		defer f.Close()
-		err := xml.NewEncoder(f).Encode(xmlSchools)
-		if err != nil {
-			return err
-		}
+	// If file name does not have file extension, add it
+	extension := fmt.Sprintf(".%s", format)
+	if !strings.HasSuffix(outputPath, extension) {
+		outputPath += extension
+	}
 
+	f, err := os.OpenFile(outputPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
 	}
+	defer f.Close()
 
-	return nil
+	return Encode(f, format, v)
 }
@@ -0,0 +1,78 @@
+package util
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testSchool struct {
+	SchoolID string `json:"schoolID" yaml:"schoolID" xml:"schoolID"`
+	Name     string `json:"name" yaml:"name" xml:"name"`
+}
+
+type testSchools struct {
+	XMLName xml.Name     `xml:"schools"`
+	Schools []testSchool `xml:"school"`
+}
+
+func TestExportFile(t *testing.T) {
+	schools := []testSchool{{"133", "Aarhus Katedralskole"}, {"681", "Hasseris Gymnasium"}}
+	tests := []struct {
+		format string
+		v      any
+		want   string
+	}{
+		{"json", schools, `[{"schoolID":"133","name":"Aarhus Katedralskole"},{"schoolID":"681","name":"Hasseris Gymnasium"}]` + "\n"},
+		{"xml", &testSchools{Schools: schools}, `<schools><school><schoolID>133</schoolID><name>Aarhus Katedralskole</name></school><school><schoolID>681</schoolID><name>Hasseris Gymnasium</name></school></schools>`},
+		{"yaml", schools, "- schoolID: \"133\"\n  name: Aarhus Katedralskole\n- schoolID: \"681\"\n  name: Hasseris Gymnasium\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "schools")
+			// A longer file at the path is truncated, not partly overwritten
+			if err := os.WriteFile(path+"."+test.format, make([]byte, 1000), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := ExportFile(test.format, path, test.v); err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(path + "." + test.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != test.want {
+				t.Errorf("Got %q, want %q", b, test.want)
+			}
+		})
+	}
+}
+
+func TestExportFileBadFormat(t *testing.T) {
+	schools := []testSchool{{"133", "Aarhus Katedralskole"}}
+	for _, format := range []string{"csv", "toml"} {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			// An existing file is left untouched, and no file is created without one
+			existing := filepath.Join(dir, "existing."+format)
+			if err := os.WriteFile(existing, []byte("old"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := ExportFile(format, existing, schools); err == nil {
+				t.Errorf("Exported %T as %s", schools, format)
+			}
+			if b, err := os.ReadFile(existing); err != nil || string(b) != "old" {
+				t.Errorf("Got %q and error %v of the existing file, want it untouched", b, err)
+			}
+
+			missing := filepath.Join(dir, "missing")
+			if err := ExportFile(format, missing, schools); err == nil {
+				t.Errorf("Exported %T as %s", schools, format)
+			}
+			if _, err := os.Stat(missing + "." + format); !os.IsNotExist(err) {
+				t.Errorf("Created %s.%s for a format that cannot be exported", missing, format)
+			}
+		})
+	}
+}